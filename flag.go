@@ -2,8 +2,11 @@ package flags
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -28,28 +31,37 @@ func Version(updateVer ...string) string {
 	return version
 }
 
-func name() string { return filepath.Base(os.Args[0]) }
+func name() string {
+	if progName != "" {
+		return progName
+	}
+	if len(os.Args) > 0 {
+		return filepath.Base(os.Args[0])
+	}
+	return "app"
+}
 
 func ParseFlags(set *FlagSet, args []string) (err error) {
-	name, out := name(), os.Stderr
+	name, version, out := nameOf(set), versionOf(set), os.Stderr
+	defer func() { err = handleError(set, out, err) }()
+	defer metaOf(set).stats.done(time.Now())
 
-	pflag.ErrHelp = fmt.Errorf("use %s [...OPTIONS] to start", name)
+	// New 创建的参数集合不修改 pflag 的全局变量
+	if metaOf(set).name == "" {
+		pflag.ErrHelp = fmt.Errorf("use %s [...OPTIONS] to start", name)
+	}
 
 	set.Init(name, pflag.ContinueOnError)
 	set.SetOutput(out)
 	set.SortFlags = false
 
+	plain := wantsPlainHelp(set, args)
 	set.Usage = func() {
-		fmt.Fprintf(out, "%s", name)
-		if version != "" {
-			fmt.Fprintf(out, " -- version %s", version)
+		if plain {
+			writePlainUsage(out, set, name)
+		} else {
+			writeUsage(out, set, name)
 		}
-		fmt.Fprintf(out, "\n\n")
-		fmt.Fprintf(out, "USAGE:\n")
-		fmt.Fprintf(out, "      %s [...OPTIONS]\n\n", name)
-		fmt.Fprintf(out, "OPTIONS:\n")
-		fmt.Fprintln(out, set.FlagUsagesWrapped(0))
-		fmt.Fprintln(out)
 	}
 
 	if set == Default() {
@@ -58,30 +70,130 @@ func ParseFlags(set *FlagSet, args []string) (err error) {
 
 	if version != "" && set.Lookup("version") == nil {
 		var shorthand string
-		if set.Lookup("v") == nil {
+		if set.ShorthandLookup("v") == nil {
 			shorthand = "v"
 		}
-		if shorthand == "" && set.Lookup("V") == nil {
+		if shorthand == "" && set.ShorthandLookup("V") == nil {
 			shorthand = "V"
 		}
 		set.BoolP("version", shorthand, false, "显示版本号")
 	}
 
-	if err = set.Parse(args); err != nil {
+	if err = defineItemFlags(set, args); err != nil {
+		return
+	}
+
+	expanded, err := expandNames(set, args)
+	if err != nil {
+		set.Usage()
+		return
+	}
+
+	if err = set.Parse(expanded); err != nil {
+		usageSuggest(out, set, err)
+		err = suggestFlags(set, err)
+		return
+	}
+	applyAliases(set)
+
+	if err = applyItemFlags(set); err != nil {
+		return
+	}
+
+	if err = applyMetadata(set); err != nil {
+		return
+	}
+
+	recordRaw(set, args)
+	applyPriority(set)
+
+	if err = checkConflicts(set); err != nil {
 		return
 	}
 
 	if ver, _ := set.GetBool("version"); ver {
-		fmt.Fprintf(out, "%s", name)
-		if version != "" {
-			fmt.Fprintf(out, " -- version %s", version)
+		writeVersion(out, name, version)
+		if !metaOf(set).hasHandling {
+			os.Exit(0)
 		}
-		os.Exit(0)
+		return ErrVersion
+	}
+
+	if err = promptMissing(set); err != nil {
+		return
+	}
+
+	if err = checkGroups(set); err != nil {
+		set.Usage()
+		return
 	}
 
+	if err = validateStructs(set); err != nil {
+		set.Usage()
+		return
+	}
+
+	if printed, e := printConfig(set); printed {
+		if e != nil {
+			return e
+		}
+		if !metaOf(set).hasHandling {
+			os.Exit(0)
+		}
+		return ErrPrintConfig
+	}
+
+	if written, e := applyLock(set); e != nil {
+		return e
+	} else if written {
+		if !metaOf(set).hasHandling {
+			os.Exit(0)
+		}
+		return ErrLockWritten
+	}
+
+	for _, fn := range metaOf(set).onParsed {
+		fn(set)
+	}
 	return
 }
 
+func writeUsage(out io.Writer, set *FlagSet, name string) {
+	fmt.Fprintf(out, "%s", name)
+	if version := versionOf(set); version != "" {
+		fmt.Fprintf(out, " -- version %s", version)
+	}
+	fmt.Fprintf(out, "\n\n")
+	fmt.Fprintf(out, "%s:\n", msg("USAGE"))
+	fmt.Fprintf(out, "      %s [...OPTIONS]\n\n", name)
+	fmt.Fprintf(out, "%s:\n", msg("OPTIONS"))
+	fmt.Fprintln(out, flagUsages(usageSet(set)))
+	fmt.Fprintln(out)
+	writeGroups(out, set)
+	writePresets(out, set)
+	writeExamples(out, set)
+}
+
+func writeExamples(out io.Writer, set *FlagSet) {
+	if examples := metaOf(set).examples; len(examples) > 0 {
+		fmt.Fprintf(out, "%s:\n", msg("EXAMPLES"))
+		for _, e := range examples {
+			if e.Desc != "" {
+				fmt.Fprintf(out, "      # %s\n", e.Desc)
+			}
+			fmt.Fprintf(out, "      %s\n\n", e.Cmd)
+		}
+	}
+}
+
+// 返回帮助信息，不输出也不退出，供 GUI 等场景使用
+func RenderHelp(flags ...*FlagSet) string {
+	var buf strings.Builder
+	set := flagSet(flags)
+	writeUsage(&buf, set, nameOf(set))
+	return buf.String()
+}
+
 func Parse() {
 	if err := ParseFlags(Default(), os.Args[1:]); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -89,6 +201,24 @@ func Parse() {
 	}
 }
 
+// 使用指定的参数解析，出错时返回错误而不是退出，用于测试和嵌入到其他程序中
+func ParseArgs(args []string, flags ...*FlagSet) error {
+	return ParseFlags(flagSet(flags), args)
+}
+
+// 在新的 FlagSet 上绑定结构体并解析指定的参数，不会修改全局的参数
+//
+// 参数和位置参数可以混合出现，需要 `tool exec -- cmd args` 这样的位置参数时，
+// 使用 StructBindE 绑定到自己的 FlagSet，再通过 Interspersed 和 ArgsAfterDash 控制
+func ParseStructArgs(structPtr any, args []string) error {
+	set := pflag.NewFlagSet(name(), pflag.ContinueOnError)
+	defer Release(set)
+	if err := StructBindE(structPtr, set); err != nil {
+		return err
+	}
+	return ParseFlags(set, args)
+}
+
 func flagSet(flags []*FlagSet) *FlagSet {
 	for _, f := range flags {
 		if f != nil {
@@ -0,0 +1,102 @@
+package flags
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 允许使用长参数名的唯一前缀，如 --verb 等同于 --verbose，和 GNU getopt_long 一致
+//
+// 完全匹配的参数优先，前缀匹配到多个参数时解析失败并列出所有候选
+func AllowAbbrev(flags ...*FlagSet) { metaOf(flagSet(flags)).abbrev = true }
+
+// 把命令行中缩写或者写法不同的长参数名替换为注册的参数名，不修改原来的 args
+//
+// 遇到 `--` 停止，关闭 Interspersed 时遇到第一个位置参数停止，之后的内容原样交给子命令
+func expandNames(set *FlagSet, args []string) ([]string, error) {
+	stopAtPositional := !interspersed(set)
+	out := append([]string(nil), args...)
+	for i := 0; i < len(out); i++ {
+		s := out[i]
+		if s == "--" {
+			break
+		}
+		if len(s) < 2 || s[0] != '-' {
+			if stopAtPositional {
+				break
+			}
+			continue
+		}
+
+		if s[1] != '-' {
+			// 短参数: 最后一个需要值的参数会使用下一个命令行参数作为值
+			for j := 1; j < len(s); j++ {
+				f := set.ShorthandLookup(s[j : j+1])
+				if f == nil || f.NoOptDefVal != "" {
+					continue
+				}
+				if j == len(s)-1 {
+					i++
+				}
+				break
+			}
+			continue
+		}
+
+		name, val, hasValue := strings.Cut(s[2:], "=")
+		if name == "" {
+			continue
+		}
+
+		f := set.Lookup(name)
+		if f == nil {
+			full, err := matchName(set, name)
+			if err != nil {
+				return nil, err
+			}
+			if full == "" {
+				continue
+			}
+			if f = set.Lookup(full); hasValue {
+				out[i] = "--" + full + "=" + val
+			} else {
+				out[i] = "--" + full
+			}
+		}
+
+		if !hasValue && f.NoOptDefVal == "" {
+			i++
+		}
+	}
+	return out, nil
+}
+
+// 按规范化后的参数名和缩写查找命令行中写的长参数名，没有找到时返回空
+func matchName(set *FlagSet, name string) (full string, err error) {
+	meta := metaOf(set)
+	if full, err = matchNormalized(set, normalizeOf(meta), name); err == nil && full == "" && meta.abbrev {
+		full, err = matchAbbrev(set, name)
+	}
+	return
+}
+
+// 按前缀查找参数，没有找到时返回空，找到多个时返回错误
+func matchAbbrev(set *FlagSet, prefix string) (name string, err error) {
+	var candidates []string
+	set.VisitAll(func(f *Flag) {
+		if strings.HasPrefix(f.Name, prefix) && f.Deprecated == "" {
+			candidates = append(candidates, f.Name)
+		}
+	})
+
+	switch len(candidates) {
+	case 0:
+		return "", nil
+	case 1:
+		return candidates[0], nil
+	default:
+		sort.Strings(candidates)
+		return "", fmt.Errorf("option '--%s' is ambiguous; possibilities: --%s", prefix, strings.Join(candidates, " --"))
+	}
+}
@@ -0,0 +1,36 @@
+package flags
+
+import "fmt"
+
+// 注册 `alias:"old-name"` 标签的旧参数名，和新参数名共用一个值，隐藏并标记为过期，使用时提示改用新的参数名
+func bindAliases(set *FlagSet, field *FlagField, item *Flag, owner string) (err error) {
+	for _, name := range field.Aliases {
+		if err = checkName(set, name); err != nil {
+			return
+		}
+		if err = checkDuplicate(set, name, owner); err != nil {
+			return
+		}
+
+		alias := set.VarPF(field.Value, name, "", item.Usage)
+		alias.NoOptDefVal = item.NoOptDefVal
+		alias.Deprecated = fmt.Sprintf("use --%s instead", item.Name)
+		alias.Hidden = true
+		meta := metaOf(set)
+		if meta.aliases == nil {
+			meta.aliases = map[string]string{}
+		}
+		meta.aliases[name] = item.Name
+		meta.owners[name] = owner
+	}
+	return
+}
+
+// 通过旧参数名设置的值，同时标记新参数为已设置，用于参数组和来源的检查
+func applyAliases(set *FlagSet) {
+	for alias, name := range metaOf(set).aliases {
+		if a, f := set.Lookup(alias), set.Lookup(name); a != nil && f != nil && a.Changed {
+			f.Changed = true
+		}
+	}
+}
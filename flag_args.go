@@ -0,0 +1,35 @@
+package flags
+
+import "reflect"
+
+// 设置参数和位置参数是否可以混合出现，默认可以。
+// 设置为 false 时遇到第一个位置参数后停止解析，之后的内容都作为位置参数，如 `tool exec cmd --cmd-flag`
+func Interspersed(enabled bool, flags ...*FlagSet) {
+	flagSet(flags).SetInterspersed(enabled)
+}
+
+// 参数和位置参数是否可以混合出现，pflag v1.0.5 没有提供读取的方法，直接读取未导出的字段
+func interspersed(set *FlagSet) bool {
+	if f := reflect.ValueOf(set).Elem().FieldByName("interspersed"); f.IsValid() && f.Kind() == reflect.Bool {
+		return f.Bool()
+	}
+	return true
+}
+
+// 命令行中 `--` 之后的位置参数，没有 `--` 时返回 nil，如 `tool exec -- cmd args` 返回 [cmd args]
+func ArgsAfterDash(flags ...*FlagSet) []string {
+	set := flagSet(flags)
+	if n := set.ArgsLenAtDash(); n >= 0 {
+		return set.Args()[n:]
+	}
+	return nil
+}
+
+// 命令行中 `--` 之前的位置参数
+func ArgsBeforeDash(flags ...*FlagSet) []string {
+	set := flagSet(flags)
+	if n := set.ArgsLenAtDash(); n >= 0 {
+		return set.Args()[:n]
+	}
+	return set.Args()
+}
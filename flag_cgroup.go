@@ -0,0 +1,90 @@
+package flags
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// cgroup 文件系统挂载的目录
+var cgroupRoot = "/sys/fs/cgroup"
+
+func init() {
+	RegisterDefault("cgroup.cpus", cgroupCPUsDefault)
+	RegisterDefault("cgroup.memory", cgroupMemoryDefault)
+}
+
+// 容器的 CPU 配额(quota/period)，兼容 cgroup v1 和 v2，未限制时 ok 为 false
+func CgroupCPUs() (cpus float64, ok bool) {
+	// v2: cpu.max 内容为 "<quota> <period>"，未限制时 quota 为 max
+	if fields := strings.Fields(readCgroup("cpu.max")); len(fields) == 2 && fields[0] != "max" {
+		return cpuQuota(fields[0], fields[1])
+	}
+	// v1: 未限制时 cfs_quota_us 为 -1
+	return cpuQuota(readCgroup("cpu/cpu.cfs_quota_us"), readCgroup("cpu/cpu.cfs_period_us"))
+}
+
+// 容器的内存限制(字节数)，兼容 cgroup v1 和 v2，未限制时 ok 为 false
+func CgroupMemory() (limit int64, ok bool) {
+	s := readCgroup("memory.max")
+	if s == "" {
+		s = readCgroup("memory/memory.limit_in_bytes")
+	}
+
+	// v1 未限制时为接近 int64 最大值的数(按页对齐)
+	if limit, err := strconv.ParseInt(s, 10, 64); err == nil && limit > 0 && limit < 1<<62 {
+		return limit, true
+	}
+	return 0, false
+}
+
+func cpuQuota(quota, period string) (float64, bool) {
+	q, e1 := strconv.ParseFloat(quota, 64)
+	p, e2 := strconv.ParseFloat(period, 64)
+	if e1 != nil || e2 != nil || q <= 0 || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
+
+func readCgroup(name string) string {
+	data, _ := os.ReadFile(filepath.Join(cgroupRoot, name))
+	return strings.TrimSpace(string(data))
+}
+
+// CPU 配额向上取整，最小为 1，未限制时为 CPU 核数，arg 为倍数
+func cgroupCPUsDefault(arg string) (string, error) {
+	n := float64(runtime.NumCPU())
+	if cpus, ok := CgroupCPUs(); ok {
+		n = math.Max(1, math.Ceil(cpus))
+	}
+
+	if arg != "" {
+		factor, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return "", err
+		}
+		n = math.Max(1, math.Ceil(n*factor))
+	}
+	return strconv.Itoa(int(n)), nil
+}
+
+// 内存限制的百分比，arg 为百分比(默认 100)，未限制时不设置
+func cgroupMemoryDefault(arg string) (string, error) {
+	limit, ok := CgroupMemory()
+	if !ok {
+		return "", nil
+	}
+
+	if arg = strings.TrimSuffix(arg, "%"); arg != "" {
+		percent, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return "", err
+		}
+		limit = int64(float64(limit) * percent / 100)
+	}
+	return strconv.FormatInt(limit, 10), nil
+}
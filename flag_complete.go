@@ -0,0 +1,84 @@
+package flags
+
+import (
+	"strings"
+)
+
+// 命令行补全的候选项，args 为已经输入的参数，最后一个为正在输入的部分(可以为空字符串)
+//
+// 输入 `-` 开头时补全参数名，输入参数值时依次使用 `validate:"oneof=..."` 的选项、bool 的 true/false，
+// 都没有时使用 `example` 标签的示例值，便于发现复杂的格式。由程序的隐藏子命令调用，输出给 shell 的补全脚本
+func Complete(args []string, flags ...*FlagSet) (out []string) {
+	set := flagSet(flags)
+	cur, prev := "", ""
+	if n := len(args); n > 0 {
+		cur = args[n-1]
+		if n > 1 {
+			prev = args[n-2]
+		}
+	}
+
+	if name, partial, ok := strings.Cut(strings.TrimPrefix(cur, "--"), "="); ok && strings.HasPrefix(cur, "--") {
+		for _, v := range completeValues(set, set.Lookup(name), partial) {
+			out = append(out, "--"+name+"="+v)
+		}
+		return
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		set.VisitAll(func(f *Flag) {
+			if name := "--" + f.Name; !f.Hidden && f.Deprecated == "" && strings.HasPrefix(name, cur) {
+				out = append(out, name)
+			}
+		})
+		return
+	}
+
+	if strings.HasPrefix(prev, "-") && !strings.Contains(prev, "=") {
+		var f *Flag
+		if name, ok := strings.CutPrefix(prev, "--"); ok {
+			f = set.Lookup(name)
+		} else if len(prev) == 2 {
+			f = set.ShorthandLookup(prev[1:])
+		}
+		if f != nil && f.NoOptDefVal == "" {
+			return completeValues(set, f, cur)
+		}
+	}
+	return
+}
+
+func completeValues(set *FlagSet, f *Flag, partial string) (out []string) {
+	if f == nil {
+		return
+	}
+
+	var candidates []string
+	if v, ok := f.Value.(*value); ok {
+		for _, r := range v.rules {
+			if r.name == "oneof" {
+				candidates = append(candidates, strings.Fields(r.arg)...)
+			}
+		}
+	}
+	if len(candidates) == 0 && f.Value.Type() == "bool" {
+		candidates = []string{"true", "false"}
+	}
+	if len(candidates) == 0 && !isSecret(set, f) {
+		if field := metaOf(set).field(f.Name); field != nil {
+			if e := getTag(field.Field.Tag, _TAG_EXAMPLE); e != "" {
+				candidates = []string{e}
+			}
+		}
+	}
+
+	for _, c := range candidates {
+		if strings.HasPrefix(c, partial) {
+			out = append(out, c)
+		}
+	}
+	return
+}
+
+// 同 Complete
+func (s *Set) Complete(args []string) []string { return Complete(args, s.FlagSet) }
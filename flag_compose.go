@@ -0,0 +1,209 @@
+package flags
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 把其他参数集合(如数据库模块导出的参数)的所有参数加入到当前参数集合
+//
+// 参数名或者短参数名冲突时不加入任何参数，返回列出所有冲突及其所属字段的错误。
+// 加入的参数和原来的参数共用同一个值，解析后写入原来绑定的结构体
+//
+// 通过 WithPrefix 给加入的参数名和环境变量加上前缀，如同一个数据库模块用于主库和从库。
+// 加了前缀的参数仍然写入原来的结构体，所以同一个参数集合只能加入一次，主库和从库各自绑定一个结构体
+func (s *Set) AddSet(other *Set, opts ...Option) error {
+	return addSet(s.FlagSet, other.FlagSet, newBindOptions(opts))
+}
+
+// 复制参数集合，复制后的参数可以单独修改 Hidden, Usage 等定义，参数值仍然绑定到原来的结构体
+func (s *Set) Clone() *Set {
+	src := metaOf(s.FlagSet)
+	c := New(nameOf(s.FlagSet))
+	dst := metaOf(c.FlagSet)
+	dst.name, dst.version = src.name, src.version
+	_ = addSet(c.FlagSet, s.FlagSet, bindOptions{})
+	return c
+}
+
+func addSet(dst, src *FlagSet, o bindOptions) error {
+	if dst == src {
+		return nil
+	}
+
+	dm, sm := metaOf(dst), metaOf(src)
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	prefix := o.prefix
+	rename := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		// 取消参数保持 no- 开头，如 --no-db-verbose
+		if rest, ok := strings.CutPrefix(name, "no-"); ok {
+			if f := src.Lookup(name); f != nil {
+				if _, neg := f.Value.(*negValue); neg {
+					return "no-" + prefix + rest
+				}
+			}
+		}
+		return prefix + name
+	}
+
+	var collisions []string
+	dropped := map[string]bool{}
+	src.VisitAll(func(f *Flag) {
+		if old := dst.Lookup(rename(f.Name)); old != nil {
+			collisions = append(collisions, fmt.Sprintf("--%s (%s, %s)", old.Name, ownerOf(dm, old.Name), ownerOf(sm, f.Name)))
+		}
+		if f.Shorthand != "" && prefix == "" {
+			if old := dst.ShorthandLookup(f.Shorthand); old != nil {
+				if dm.dropShorthand {
+					warnShorthandDropped(f.Shorthand, old.Name, ownerOf(dm, old.Name), f.Name, ownerOf(sm, f.Name))
+					dropped[f.Name] = true
+				} else {
+					collisions = append(collisions, fmt.Sprintf("-%s (%s, %s)", f.Shorthand, ownerOf(dm, old.Name), ownerOf(sm, f.Name)))
+				}
+			}
+		}
+	})
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return fmt.Errorf("flag collisions: %s", strings.Join(collisions, ", "))
+	}
+	if dm.added[src] {
+		return fmt.Errorf("flags of %s already added; bind a separate struct for each prefix", nameOf(src))
+	}
+
+	clones := map[*value]*value{}
+	for name, field := range sm.fields {
+		if prefix != "" {
+			// 复制字段和值，恢复为读取环境变量之前的值，再按加了前缀的环境变量重新读取，不修改原来的参数集合
+			c := *field
+			c.Value = field.Value.clone()
+			c.Value.flags = dst
+			if c.Value.envKey != "" {
+				if err := c.Value.restore(c.Value.envBase, c.Value.envBaseSource, c.Value.envBaseKey); err != nil {
+					return fmt.Errorf("flag --%s: %w", name, err)
+				}
+				c.Value.envKey, c.Value.envVal = "", ""
+			}
+			prefixField(&c, prefix)
+			c.UpdateFromEnv()
+			clones[field.Value] = c.Value
+			field = &c
+		}
+		dm.fields[rename(name)] = field
+	}
+
+	// 预设的参数名也加上前缀，展开时写入当前参数集合
+	presets := map[*preset]*preset{}
+	for _, p := range sm.presets {
+		np := &preset{name: rename(p.name), values: map[string]string{}}
+		for k, s := range p.values {
+			np.values[rename(k)] = s
+		}
+		presets[p] = np
+	}
+
+	src.VisitAll(func(f *Flag) {
+		c := *f
+		c.Changed = false
+		if pv, ok := f.Value.(*presetValue); ok && presets[pv.preset] != nil {
+			np := presets[pv.preset]
+			c.Name, c.Value, c.Usage = np.name, &presetValue{set: dst, preset: np}, "preset: "+np.String()
+			dst.AddFlag(&c)
+			return
+		}
+		if prefix != "" {
+			c.Name, c.Shorthand, c.ShorthandDeprecated = rename(f.Name), "", ""
+			switch v := f.Value.(type) {
+			case *value:
+				if cv := clones[v]; cv != nil {
+					c.Value = cv
+				}
+			case *negValue:
+				if cv := clones[v.target]; cv != nil {
+					c.Value = &negValue{target: cv}
+				}
+			}
+			c.DefValue = c.Value.String()
+		}
+		if dropped[f.Name] {
+			c.Shorthand, c.ShorthandDeprecated = "", ""
+		}
+		dst.AddFlag(&c)
+	})
+
+	for name, owner := range sm.owners {
+		dm.owners[rename(name)] = owner
+	}
+	for alias, name := range sm.aliases {
+		if dm.aliases == nil {
+			dm.aliases = map[string]string{}
+		}
+		dm.aliases[rename(alias)] = rename(name)
+	}
+	for key, users := range sm.envUsers {
+		if prefix != "" {
+			key = envPrefix(prefix) + key
+		}
+		for _, name := range users {
+			dm.envUsers[key] = append(dm.envUsers[key], rename(name))
+		}
+	}
+	for name := range sm.secrets {
+		if dm.secrets == nil {
+			dm.secrets = map[string]bool{}
+		}
+		dm.secrets[rename(name)] = true
+	}
+	for name, message := range sm.prompts {
+		if dm.prompts == nil {
+			dm.prompts = map[string]string{}
+		}
+		dm.prompts[rename(name)] = message
+	}
+
+	for _, g := range sm.groups {
+		if prefix != "" {
+			c := &flagGroup{kind: g.kind, tag: g.tag}
+			if c.tag != "" {
+				c.tag = prefix + c.tag
+			}
+			for _, name := range g.names {
+				c.names = append(c.names, rename(name))
+			}
+			g = c
+		}
+		dm.groups = append(dm.groups, g)
+	}
+	for _, sl := range sm.slices {
+		if prefix != "" {
+			sl = &structSlice{name: prefix + sl.name, v: sl.v}
+		}
+		dm.slices = append(dm.slices, sl)
+	}
+
+	dm.examples = append(dm.examples, sm.examples...)
+	dm.structs = append(dm.structs, sm.structs...)
+	for _, p := range sm.presets {
+		dm.presets = append(dm.presets, presets[p])
+	}
+	dm.onParsed = append(dm.onParsed, sm.onParsed...)
+	if dm.added == nil {
+		dm.added = map[*FlagSet]bool{}
+	}
+	dm.added[src] = true
+	return nil
+}
+
+// 参数所属的字段，不是绑定的结构体字段时为参数名
+func ownerOf(meta *setMeta, name string) string {
+	if owner := meta.owners[name]; owner != "" {
+		return owner
+	}
+	return "--" + name
+}
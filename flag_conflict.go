@@ -0,0 +1,63 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type conflictMode int
+
+const (
+	conflictOff conflictMode = iota
+	conflictWarn
+	conflictError
+)
+
+// 开启来源一致性检查，同一个参数同时通过环境变量和命令行设置且值不同时输出警告，strict 为 true 时解析返回错误
+func CheckConflicts(strict bool, flags ...*FlagSet) {
+	mode := conflictWarn
+	if strict {
+		mode = conflictError
+	}
+	metaOf(flagSet(flags)).conflict = mode
+}
+
+func checkConflicts(set *FlagSet) error {
+	meta := metaOf(set)
+	if meta.conflict == conflictOff {
+		return nil
+	}
+
+	var lines []string
+	for name, field := range meta.fieldList() {
+		v := field.Value
+		v.mu.Lock()
+		changed, envKey, envVal, args := v.changed, v.envKey, v.envVal, strings.Join(v.args, ",")
+		v.mu.Unlock()
+		if !changed || envKey == "" || strings.Join(v.split(envVal), ",") == args {
+			continue
+		}
+
+		// 敏感参数只显示是否有值
+		if f := set.Lookup(name); f != nil && isSecret(set, f) {
+			args, envVal = redactValue(args), redactValue(envVal)
+		}
+		lines = append(lines, fmt.Sprintf("--%s: flag=%s, env %s=%s", name, args, envKey, envVal))
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+	sort.Strings(lines)
+
+	if meta.conflict == conflictError {
+		return fmt.Errorf("conflicting values from env and flag: %s", strings.Join(lines, "; "))
+	}
+
+	for _, line := range lines {
+		fmt.Fprintf(os.Stderr, "[WARN] 参数的命令行值和环境变量不一致，使用命令行的值 %s\n", line)
+	}
+	return nil
+}
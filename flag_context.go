@@ -0,0 +1,47 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// 同 Parse，远程配置的获取、交互输入和监听等操作会在 ctx 取消或者超时后停止
+func ParseContext(ctx context.Context) {
+	if err := ParseFlagsContext(ctx, Default(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// 同 ParseFlags，ctx 会传递给解析中的所有操作，解析完成后仍然用于后台的监听
+func ParseFlagsContext(ctx context.Context, set *FlagSet, args []string) (err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	meta := metaOf(set)
+	metasMu.Lock()
+	meta.ctx = ctx
+	metasMu.Unlock()
+	if err = ParseFlags(set, args); err != nil {
+		return
+	}
+	return ctx.Err()
+}
+
+// 解析时使用的 context，没有通过 ParseFlagsContext 解析时为 context.Background()
+//
+// 解析时会在持有参数的锁时读取，所以 ctx 由 metasMu 保护，而不是 meta.mu
+func contextOf(set *FlagSet) context.Context {
+	if set != nil {
+		meta := metaOf(set)
+		metasMu.Lock()
+		ctx := meta.ctx
+		metasMu.Unlock()
+		if ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
+}
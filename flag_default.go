@@ -0,0 +1,59 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 计算参数默认值的函数，arg 为标签中 `:` 之后的部分，返回空字符串时保留字段原来的值
+type DefaultFunc func(arg string) (string, error)
+
+var defaultFuncs map[string]DefaultFunc
+
+// 注册默认值函数，字段通过标签 `defaultFrom:"<name>[:<arg>]"` 使用，也可以写作 `defaultfrom`
+//
+// 内置的函数:
+//
+//	cgroup.cpus[:n]     容器的 CPU 配额(向上取整)乘以 n，未限制时为 CPU 核数
+//	cgroup.memory[:n]   容器内存限制的 n%(字节数)，未限制时不设置
+//	hostname            主机名
+//	fqdn                主机的完整域名，解析失败时为主机名
+//	outbound-ip[:addr]  访问 addr 时使用的本机 IP，默认 8.8.8.8:53
+//	freeport[:host]     一个空闲的 TCP 端口，默认监听 127.0.0.1
+func RegisterDefault(name string, fn DefaultFunc) {
+	if defaultFuncs == nil {
+		defaultFuncs = map[string]DefaultFunc{}
+	}
+	defaultFuncs[name] = fn
+}
+
+// 绑定时调用默认值函数，优先级低于 Downward API 文件和环境变量
+func fieldDefaultFrom(field *FlagField) {
+	tag := getTag(field.Field.Tag, _TAG_DEFAULT_FROM)
+	if tag == "" {
+		tag = getTag(field.Field.Tag, _TAG_DEFAULT_FROM_ALIAS)
+	}
+	if tag == "" || tag == "-" {
+		return
+	}
+
+	name, arg, _ := strings.Cut(tag, ":")
+	fn, found := defaultFuncs[name]
+	if !found {
+		fmt.Fprintf(os.Stderr, "[WARN] 参数[%s]的默认值函数[%s]未注册\n", field.Name, name)
+		return
+	}
+
+	s, err := fn(arg)
+	if err == nil && s != "" {
+		err = field.Value.SetDefault(field.Value.split(s)...)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] 参数[%s]的默认值函数[%s]出错: %v\n", field.Name, name, err)
+		return
+	}
+	if s != "" {
+		field.Value.setSource(SourceDefault, name)
+	}
+}
@@ -0,0 +1,63 @@
+package flags
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterDefault("hostname", func(string) (string, error) { return os.Hostname() })
+	RegisterDefault("fqdn", fqdnDefault)
+	RegisterDefault("outbound-ip", outboundIPDefault)
+	RegisterDefault("freeport", freePortDefault)
+}
+
+// 主机的完整域名，反向解析失败时为主机名
+func fqdnDefault(string) (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	addrs, _ := net.LookupHost(host)
+	for _, addr := range addrs {
+		if names, _ := net.LookupAddr(addr); len(names) > 0 {
+			if name := strings.TrimSuffix(names[0], "."); strings.Contains(name, ".") {
+				return name, nil
+			}
+		}
+	}
+	return host, nil
+}
+
+// 访问外部地址时使用的本机 IP，arg 为目标地址，默认 8.8.8.8:53
+//
+// 使用 UDP 只是选择路由，不会真的发送数据
+func outboundIPDefault(arg string) (string, error) {
+	if arg == "" {
+		arg = "8.8.8.8:53"
+	}
+
+	conn, err := net.Dial("udp", arg)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// 一个空闲的 TCP 端口，arg 为监听的地址，默认 127.0.0.1
+func freePortDefault(arg string) (string, error) {
+	if arg == "" {
+		arg = "127.0.0.1"
+	}
+
+	l, err := net.Listen("tcp", net.JoinHostPort(arg, "0"))
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port), nil
+}
@@ -0,0 +1,97 @@
+package flags
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Downward API 文件默认挂载的目录
+const DefaultDownwardDir = "/etc/podinfo"
+
+// 设置之后绑定的参数读取 Downward API 文件的目录
+func DownwardDir(dir string, flags ...*FlagSet) { metaOf(flagSet(flags)).downward = dir }
+
+// 标签 `downward:"<file>[/<key>]"`，绑定时从 Kubernetes Downward API 挂载的文件读取默认值，优先级低于环境变量
+//
+//	downward:"namespace"      读取整个文件，如 metadata.namespace
+//	downward:"labels"         map[string]string 字段读取全部的 labels 或者 annotations
+//	downward:"labels/app"     读取 labels 中的 app
+//
+// 文件不存在时忽略，如不在 Kubernetes 中运行
+func fieldDownward(set *FlagSet, field *FlagField) {
+	tag := getTag(field.Field.Tag, _TAG_DOWNWARD)
+	if tag == "" || tag == "-" {
+		return
+	}
+	defer metaOf(set).stats.load(SourceMetadata, time.Now())
+
+	dir := metaOf(set).downward
+	if dir == "" {
+		dir = DefaultDownwardDir
+	}
+
+	file, key, _ := strings.Cut(tag, "/")
+	data, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return
+	}
+
+	var args []string
+	switch {
+	case key != "":
+		if v, ok := downwardItems(data)[key]; ok {
+			args = []string{v}
+		}
+	case field.Value.DirectType().Kind() == reflect.Map:
+		items := downwardItems(data)
+		for _, k := range sortedKeys(items) {
+			args = append(args, k+"="+items[k])
+		}
+	default:
+		args = []string{strings.TrimSpace(string(data))}
+	}
+
+	if len(args) == 0 {
+		return
+	}
+
+	if err = field.Value.SetDefault(args...); err != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] Downward API 文件[%s]的值无效: %v\n", file, err)
+		return
+	}
+	field.Value.setSource(SourceMetadata, file)
+}
+
+// 解析 labels, annotations 文件，每行一个 key="value"
+func downwardItems(data []byte) map[string]string {
+	items := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		if s, err := strconv.Unquote(v); err == nil {
+			v = s
+		}
+		items[strings.TrimSpace(k)] = v
+	}
+	return items
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,22 @@
+package flags
+
+import "os"
+
+var (
+	progName string
+	environ  map[string]string
+)
+
+// 显式初始化程序名和环境变量，用于 gomobile 等 os.Args 和环境变量不可用的场景
+//
+// env 为 nil 时使用进程的环境变量
+func Init(name string, env map[string]string) {
+	progName, environ = name, env
+}
+
+func getenv(key string) string {
+	if environ != nil {
+		return environ[key]
+	}
+	return os.Getenv(key)
+}
@@ -0,0 +1,44 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+)
+
+// 通过 --version 显示了版本号，设置了 ErrorHandling 时 ParseFlags 返回该错误而不是退出
+var ErrVersion = errors.New("version requested")
+
+// 设置 ParseFlags 出错时的处理方式
+//
+//	pflag.ContinueOnError: 返回错误，包括 pflag.ErrHelp 和 ErrVersion，不会退出
+//	pflag.ExitOnError:     输出错误并退出，--help、--version 和 --print-config 退出码为 0，其他错误为 2
+//	pflag.PanicOnError:    panic
+//
+// 未设置时保持原来的行为: 返回错误，--version 显示版本号后退出
+func ErrorHandling(h pflag.ErrorHandling, flags ...*FlagSet) {
+	meta := metaOf(flagSet(flags))
+	meta.handling, meta.hasHandling = h, true
+}
+
+func handleError(set *FlagSet, out io.Writer, err error) error {
+	meta := metaOf(set)
+	if err == nil || !meta.hasHandling {
+		return err
+	}
+
+	switch meta.handling {
+	case pflag.ExitOnError:
+		if errors.Is(err, pflag.ErrHelp) || errors.Is(err, ErrVersion) || errors.Is(err, ErrPrintConfig) || errors.Is(err, ErrLockWritten) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(out, err)
+		os.Exit(2)
+	case pflag.PanicOnError:
+		panic(err)
+	}
+	return err
+}
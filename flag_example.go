@@ -0,0 +1,16 @@
+package flags
+
+type example struct {
+	Desc string
+	Cmd  string
+}
+
+// 添加使用示例，显示在帮助信息的 EXAMPLES 部分
+//
+// 结构体字段的 `example` 标签只显示在参数说明中，不会出现在 EXAMPLES 中
+//
+//	flags.Example("run with custom port", "app --port 9090")
+func Example(desc, cmd string, flags ...*FlagSet) {
+	meta := metaOf(flagSet(flags))
+	meta.examples = append(meta.examples, example{Desc: desc, Cmd: cmd})
+}
@@ -0,0 +1,41 @@
+package flags
+
+import "os"
+
+// 生成启动子进程的命令行参数，重现当前所有非默认值的配置，如 `--port=8080`，切片的每个值单独一个参数
+//
+// 有环境变量名的敏感参数不放在命令行中(会被 ps 等看到)，通过 ExecEnv 传递
+func ExecArgs(flags ...*FlagSet) (args []string) {
+	set := flagSet(flags)
+	meta := metaOf(set)
+	set.VisitAll(func(f *Flag) {
+		field := meta.field(f.Name)
+		if field == nil {
+			return
+		}
+
+		v := field.Value
+		values := v.current()
+		marked := meta.markedSecret(f.Name)
+		v.mu.Lock()
+		src, secret := v.source, v.secret || marked
+		v.mu.Unlock()
+		if src == SourceDefault || (secret && len(envKeys(field)) > 0) {
+			return
+		}
+
+		for _, s := range values {
+			args = append(args, "--"+f.Name+"="+s)
+		}
+	})
+	return
+}
+
+// 生成启动子进程的环境变量，在当前进程的环境变量基础上加上非默认值的参数，同 InheritEnv(os.Environ(), flags...)
+func ExecEnv(flags ...*FlagSet) []string { return InheritEnv(os.Environ(), flags...) }
+
+// 同 ExecArgs
+func (s *Set) ExecArgs() []string { return ExecArgs(s.FlagSet) }
+
+// 同 ExecEnv
+func (s *Set) ExecEnv() []string { return ExecEnv(s.FlagSet) }
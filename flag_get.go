@@ -0,0 +1,66 @@
+package flags
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/spf13/pflag"
+)
+
+// 可以按参数名查找参数，*FlagSet 和 *Set 都可以
+type flagLookup interface {
+	Lookup(name string) *Flag
+}
+
+// 按类型读取参数的当前值，不需要保留绑定的结构体，也不需要 pflag 的 GetInt, GetString 等方法
+//
+// T 和字段的类型相同时直接返回，否则按参数的文本值转换，如 Get[string] 可以读取任何参数的文本值。
+// 可以在多个 goroutine 中调用
+func Get[T any](fs flagLookup, name string) (out T, err error) {
+	f := fs.Lookup(name)
+	if f == nil {
+		return out, fmt.Errorf("flag %q not defined", name)
+	}
+
+	var args []string
+	switch v := f.Value.(type) {
+	case *value:
+		v.mu.Lock()
+		rv := reflect.Indirect(v.v)
+		if rv.IsValid() && rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		if rv.IsValid() && rv.Type() == reflect.TypeOf(out) {
+			out = rv.Interface().(T)
+			v.mu.Unlock()
+			return
+		}
+		v.sync()
+		args = append(args, v.args...)
+		v.mu.Unlock()
+	case pflag.SliceValue:
+		args = v.GetSlice()
+	default:
+		args = []string{f.Value.String()}
+	}
+
+	target := reflect.ValueOf(&out).Elem()
+	if !isAllow(target.Type()) {
+		return out, fmt.Errorf("unsupported type %s for flag --%s", target.Type(), name)
+	}
+	for i, s := range args {
+		if err = rSets(target, s, i == 0); err != nil {
+			return out, fmt.Errorf("flag --%s: %w", name, err)
+		}
+	}
+	return
+}
+
+// 同 Get，出错时 panic，用于参数名和类型都确定的场景
+func MustGet[T any](fs flagLookup, name string) T {
+	v, err := Get[T](fs, name)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
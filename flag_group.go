@@ -0,0 +1,163 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type groupKind int
+
+const (
+	groupExclusive groupKind = iota
+	groupOneRequired
+	groupRequires // names[0] 依赖其余的参数
+)
+
+type flagGroup struct {
+	kind  groupKind
+	tag   string // 通过标签定义的分组名
+	names []string
+}
+
+func (g *flagGroup) String() string {
+	names := "--" + strings.Join(g.names, ", --")
+	switch g.kind {
+	case groupRequires:
+		return "--" + g.names[0] + " requires --" + strings.Join(g.names[1:], ", --")
+	case groupExclusive:
+		return names + ": mutually exclusive"
+	default:
+		return names + ": one required"
+	}
+}
+
+// 标记一组参数互斥，命令行中最多只能设置其中一个
+func MarkMutuallyExclusive(set *FlagSet, names ...string) error {
+	return addGroup(set, groupExclusive, "", names...)
+}
+
+// 标记一组参数至少需要设置其中一个
+func MarkOneRequired(set *FlagSet, names ...string) error {
+	return addGroup(set, groupOneRequired, "", names...)
+}
+
+// 标记参数依赖其他参数，设置了 name 时 requires 也必须设置
+func MarkRequires(set *FlagSet, name string, requires ...string) error {
+	return addGroup(set, groupRequires, "", append([]string{name}, requires...)...)
+}
+
+func addGroup(set *FlagSet, kind groupKind, tag string, names ...string) error {
+	for _, name := range names {
+		if set.Lookup(name) == nil {
+			return fmt.Errorf("flag %q not defined", name)
+		}
+	}
+
+	meta := metaOf(set)
+	if tag != "" {
+		for _, g := range meta.groups {
+			if g.kind == kind && g.tag == tag {
+				g.names = append(g.names, names...)
+				return nil
+			}
+		}
+	}
+	meta.groups = append(meta.groups, &flagGroup{kind: kind, tag: tag, names: names})
+	return nil
+}
+
+func fieldGroups(set *FlagSet, field *FlagField) (err error) {
+	// 按固定顺序处理，分组的顺序和校验错误都是确定的
+	for _, g := range []struct {
+		kind groupKind
+		tag  string
+	}{{groupExclusive, _TAG_EXCLUSIVE}, {groupOneRequired, _TAG_ONE_REQUIRED}} {
+		for _, tag := range fieldSpilt(getTag(field.Field.Tag, g.tag)) {
+			if err = addGroup(set, g.kind, field.prefix+tag, field.Name); err != nil {
+				return
+			}
+		}
+	}
+
+	if requires := fieldSpilt(getTag(field.Field.Tag, _TAG_REQUIRES)); len(requires) > 0 {
+		for i, name := range requires {
+			requires[i] = field.prefix + name
+		}
+		// 依赖的参数可能在后面才定义，在解析时才检查是否存在
+		meta := metaOf(set)
+		meta.groups = append(meta.groups, &flagGroup{kind: groupRequires, names: append([]string{field.Name}, requires...)})
+	}
+	return
+}
+
+// 参数是否有值，命令行设置或者来自默认值、环境变量
+func isProvided(f *Flag) bool {
+	if f.Changed {
+		return true
+	}
+	if v, ok := f.Value.(*value); ok {
+		return len(v.args) > 0
+	}
+	return f.Value.String() != f.DefValue
+}
+
+func checkGroups(set *FlagSet) error {
+	for _, g := range metaOf(set).groups {
+		if g.kind == groupRequires {
+			if err := checkRequires(set, g.names[0], g.names[1:]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var provided []string
+		for _, name := range g.names {
+			if f := set.Lookup(name); f != nil {
+				if (g.kind == groupExclusive && f.Changed) || (g.kind == groupOneRequired && isProvided(f)) {
+					provided = append(provided, "--"+name)
+				}
+			}
+		}
+
+		switch {
+		case g.kind == groupExclusive && len(provided) > 1:
+			return fmt.Errorf("flags %s are mutually exclusive", strings.Join(provided, ", "))
+		case g.kind == groupOneRequired && len(provided) == 0:
+			return fmt.Errorf("one of flags --%s is required", strings.Join(g.names, ", --"))
+		}
+	}
+	return nil
+}
+
+func checkRequires(set *FlagSet, name string, requires []string) error {
+	if f := set.Lookup(name); f == nil || !isProvided(f) {
+		return nil
+	}
+
+	var missing []string
+	for _, r := range requires {
+		f := set.Lookup(r)
+		if f == nil {
+			return fmt.Errorf("flag --%s requires undefined flag --%s", name, r)
+		}
+		if !isProvided(f) {
+			missing = append(missing, "--"+r)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("flag --%s requires %s to be set", name, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func writeGroups(out io.Writer, set *FlagSet) {
+	if groups := metaOf(set).groups; len(groups) > 0 {
+		fmt.Fprintf(out, "%s:\n", msg("GROUPS"))
+		for _, g := range groups {
+			fmt.Fprintf(out, "      %s\n", g)
+		}
+		fmt.Fprintln(out)
+	}
+}
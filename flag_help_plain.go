@@ -0,0 +1,91 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// 命令行中是否有 --help=plain，`--` 之后的不算
+func wantsPlainHelp(set *FlagSet, args []string) bool {
+	if set.Lookup("help") != nil {
+		return false
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--help=plain" {
+			return true
+		}
+	}
+	return false
+}
+
+// 无障碍的帮助信息，通过 --help=plain 输出
+//
+// 不使用表格对齐和颜色，每个参数一段，每行一个 `标签: 值`，屏幕阅读器可以逐行朗读
+func writePlainUsage(out io.Writer, set *FlagSet, name string) {
+	meta := metaOf(set)
+
+	fmt.Fprintf(out, "%s\n", name)
+	if version := versionOf(set); version != "" {
+		fmt.Fprintf(out, "%s: %s\n", msg("Version"), version)
+	}
+	fmt.Fprintf(out, "%s: %s [...OPTIONS]\n\n", msg("USAGE"), name)
+	fmt.Fprintf(out, "%s:\n\n", msg("OPTIONS"))
+
+	set.VisitAll(func(f *Flag) {
+		if f.Hidden {
+			return
+		}
+
+		fmt.Fprintf(out, "%s: --%s\n", msg("Flag"), f.Name)
+		if f.Shorthand != "" && f.ShorthandDeprecated == "" {
+			fmt.Fprintf(out, "%s: -%s\n", msg("Shorthand"), f.Shorthand)
+		}
+		if typ, _ := typeName(f); typ != "" {
+			fmt.Fprintf(out, "%s: %s\n", msg("Type"), typ)
+		}
+
+		_, usage := pflag.UnquoteUsage(f)
+		field := meta.field(f.Name)
+		if field != nil {
+			if usage = field.Usage; usage == "" {
+				usage = field.Field.Name
+			}
+		}
+		if usage != "" {
+			fmt.Fprintf(out, "%s: %s\n", msg("Description"), usage)
+		}
+
+		if !isZeroDefault(f) {
+			def := f.DefValue
+			if isSecret(set, f) {
+				def = redacted
+			}
+			fmt.Fprintf(out, "%s: %s\n", msg("Default"), def)
+		}
+		if field != nil && len(field.Env) > 0 {
+			fmt.Fprintf(out, "%s: %s\n", msg("Environment"), strings.Join(field.Env, ", "))
+		}
+		if f.Deprecated != "" {
+			fmt.Fprintf(out, "%s: %s\n", msg("Deprecated"), f.Deprecated)
+		}
+		fmt.Fprintln(out)
+	})
+
+	writeGroups(out, set)
+	writePresets(out, set)
+	writeExamples(out, set)
+}
+
+// 返回 --help=plain 的帮助信息，不输出也不退出
+func RenderPlainHelp(flags ...*FlagSet) string {
+	set := flagSet(flags)
+	var buf strings.Builder
+	writePlainUsage(&buf, set, nameOf(set))
+	return buf.String()
+}
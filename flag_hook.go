@@ -0,0 +1,25 @@
+package flags
+
+import "fmt"
+
+// 参数的值改变时调用 fn，包括命令行、配置文件和 SetAll 等方式的修改，多个值以 `,` 连接
+func OnSet(name string, fn func(old, new string), flags ...*FlagSet) error {
+	f := flagSet(flags).Lookup(name)
+	if f == nil {
+		return fmt.Errorf("flag %q not defined", name)
+	}
+
+	v, ok := f.Value.(*value)
+	if !ok {
+		return fmt.Errorf("flag %q is not bound by this package", name)
+	}
+
+	v.onSet = append(v.onSet, fn)
+	return nil
+}
+
+// ParseFlags 解析和校验全部成功后调用 fn
+func OnParsed(fn func(set *FlagSet), flags ...*FlagSet) {
+	meta := metaOf(flagSet(flags))
+	meta.onParsed = append(meta.onParsed, fn)
+}
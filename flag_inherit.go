@@ -0,0 +1,64 @@
+package flags
+
+import (
+	"sort"
+	"strings"
+)
+
+// 记录由父进程传递的环境变量名的环境变量，子进程读取到这些环境变量时，来源记为 SourceParent
+var InheritedEnvKey = "FLAGS_INHERITED_ENV"
+
+// 生成传递给子进程的环境变量，如管理进程启动的 worker 进程，base 通常为 os.Environ()
+//
+// 非默认值的参数以第一个环境变量名传递，同时通过 InheritedEnvKey 记录传递的环境变量名，
+// 子进程中这些参数的来源为 SourceParent，和运维直接设置的环境变量区分。多个值的切片无法通过一个环境变量表示，不会传递
+func InheritEnv(base []string, flags ...*FlagSet) (env []string) {
+	meta := metaOf(flagSet(flags))
+
+	values := map[string]string{}
+	for _, field := range meta.fieldList() {
+		keys := envKeys(field)
+		if len(keys) == 0 {
+			continue
+		}
+
+		v := field.Value
+		args := v.current()
+		v.mu.Lock()
+		src := v.source
+		v.mu.Unlock()
+		if src != SourceDefault && len(args) == 1 {
+			values[keys[0]] = args[0]
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := values[key]; !ok && key != InheritedEnvKey {
+			env = append(env, kv)
+		}
+	}
+	for _, key := range keys {
+		env = append(env, key+"="+values[key])
+	}
+	if len(keys) > 0 {
+		env = append(env, InheritedEnvKey+"="+strings.Join(keys, ","))
+	}
+	return
+}
+
+// 环境变量的来源，由父进程通过 InheritEnv 传递的为 SourceParent
+func envSource(key string) Source {
+	for _, k := range strings.Split(getenv(InheritedEnvKey), ",") {
+		if k == key {
+			return SourceParent
+		}
+	}
+	return SourceEnv
+}
@@ -0,0 +1,30 @@
+package flags
+
+import (
+	"reflect"
+
+	"github.com/spf13/pflag"
+)
+
+// 只读模式，按结构体的定义生成一个新的 FlagSet，用于生成文档和导出结构等场景
+//
+// 绑定的是结构体的副本，不会修改原结构体，也不会读取环境变量。
+// 生成文档等需要用到绑定的信息，用完后调用 Release 释放
+func StructInspect(structPtr any) (set *FlagSet, err error) {
+	v, err := structValue(structPtr)
+	if err != nil {
+		return
+	}
+
+	cp := reflect.New(v.Type())
+	cp.Elem().Set(v)
+
+	set = pflag.NewFlagSet(rType(v.Type(), true), pflag.ContinueOnError)
+	metaOf(set).inspect = true
+
+	if err = StructBindE(cp.Interface(), set); err != nil {
+		Release(set)
+		return nil, err
+	}
+	return
+}
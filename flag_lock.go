@@ -0,0 +1,178 @@
+package flags
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 通过 --write-lock 写入了锁文件，设置了 ErrorHandling 时 ParseFlags 返回该错误而不是退出
+var ErrLockWritten = errors.New("lock file written")
+
+// 锁文件的内容，记录最终的配置和配置来源文件的哈希
+type Lock struct {
+	Salt    string              `json:"salt,omitempty"` // 敏感参数 HMAC 的随机盐，和哈希保存在同一个文件中，只能防止预先计算的彩虹表，不能防止对短密码的暴力破解
+	Values  map[string][]string `json:"values"`         // 参数名 => 最终的值，敏感参数只记录 HMAC
+	Sources map[string]string   `json:"sources"`        // 配置文件、Downward API 文件的路径 => 内容的哈希，读取失败时为 unverifiable
+}
+
+// 无法读取的配置来源在锁文件中的记录
+const unverifiableSource = "unverifiable"
+
+// 注册 --write-lock 和 --verify-lock 参数，用于需要可重现配置的批处理任务
+//
+// --write-lock=path 在解析完成后写入锁文件并退出，--verify-lock=path 检查当前配置和锁文件是否一致，不一致时解析返回错误
+func LockFlags(hidden bool, flags ...*FlagSet) {
+	set := flagSet(flags)
+	set.String("write-lock", "", "写入锁文件(最终的配置和配置文件的哈希)并退出")
+	set.String("verify-lock", "", "检查配置是否和锁文件一致")
+	set.Lookup("write-lock").Hidden = hidden
+	set.Lookup("verify-lock").Hidden = hidden
+}
+
+// 解析完成后处理 --write-lock 和 --verify-lock
+func applyLock(set *FlagSet) (written bool, err error) {
+	if f := set.Lookup("write-lock"); f != nil && f.Changed {
+		return true, WriteLock(f.Value.String(), set)
+	}
+	if f := set.Lookup("verify-lock"); f != nil && f.Changed {
+		return false, VerifyLock(f.Value.String(), set)
+	}
+	return
+}
+
+// 当前配置的锁，敏感参数使用新生成的随机盐计算 HMAC，能读取锁文件的人仍然可以逐个尝试猜测短的密码
+func CurrentLock(flags ...*FlagSet) *Lock {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	return currentLock(flagSet(flags), hex.EncodeToString(salt))
+}
+
+func currentLock(set *FlagSet, salt string) *Lock {
+	meta := metaOf(set)
+	lock := &Lock{Salt: salt, Values: map[string][]string{}, Sources: map[string]string{}}
+
+	// 远程的配置文件(scp、git、对象存储)也重新读取计算哈希
+	ctx := contextOf(set)
+	addSource := func(path string) {
+		if data, err := readBytes(ctx, path, func([]byte) error { return nil }); err == nil {
+			lock.Sources[path] = hashOf(string(data))
+		} else {
+			lock.Sources[path] = unverifiableSource
+		}
+	}
+
+	for name, field := range meta.fieldList() {
+		v := field.Value
+		values := v.current()
+		marked := meta.markedSecret(name)
+		v.mu.Lock()
+		src, key, secret := v.source, v.sourceKey, v.secret || marked
+		v.mu.Unlock()
+
+		if secret {
+			values = []string{secretHash(salt, strings.Join(values, "\x00"))}
+		}
+		lock.Values[name] = values
+		if key != "" && (src == SourceConfig || src == SourceMetadata && !isCloudMetadataKey(key)) {
+			addSource(key)
+		}
+	}
+
+	set.VisitAll(func(f *Flag) {
+		if b, ok := f.Value.(*configFileValue); ok && b.path != "" {
+			_, path := getCotentType(b.path)
+			addSource(path)
+		}
+	})
+	return lock
+}
+
+// 写入当前配置的锁文件
+func WriteLock(path string, flags ...*FlagSet) error {
+	data, err := json.MarshalIndent(CurrentLock(flags...), "", "  ")
+	if err != nil {
+		return err
+	}
+	// 包含敏感参数的 HMAC 和密钥，只允许自己读取
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// 检查当前配置和锁文件是否一致，不一致时返回列出所有差异的错误
+func VerifyLock(path string, flags ...*FlagSet) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var locked Lock
+	if err = json.Unmarshal(data, &locked); err != nil {
+		return fmt.Errorf("parse lock file %s: %w", path, err)
+	}
+
+	cur := currentLock(flagSet(flags), locked.Salt)
+	var drift []string
+	for name, want := range locked.Values {
+		got, ok := cur.Values[name]
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("--%s: not defined", name))
+		case strings.Join(got, "\x00") != strings.Join(want, "\x00"):
+			if w := strings.Join(want, ""); strings.HasPrefix(w, "hmac-sha256:") || strings.HasPrefix(w, "sha256:") {
+				drift = append(drift, fmt.Sprintf("--%s: changed", name))
+			} else {
+				drift = append(drift, fmt.Sprintf("--%s: %s => %s", name, strings.Join(want, ","), strings.Join(got, ",")))
+			}
+		}
+	}
+	for name := range cur.Values {
+		if _, ok := locked.Values[name]; !ok {
+			drift = append(drift, fmt.Sprintf("--%s: not in lock", name))
+		}
+	}
+	for src, want := range locked.Sources {
+		if got, ok := cur.Sources[src]; !ok {
+			drift = append(drift, fmt.Sprintf("%s: not loaded", src))
+		} else if got == unverifiableSource || want == unverifiableSource {
+			drift = append(drift, fmt.Sprintf("%s: cannot be verified", src))
+		} else if got != want {
+			drift = append(drift, fmt.Sprintf("%s: content changed", src))
+		}
+	}
+
+	if len(drift) == 0 {
+		return nil
+	}
+	sort.Strings(drift)
+	return fmt.Errorf("config drift from lock file %s: %s", path, strings.Join(drift, "; "))
+}
+
+func hashOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// 敏感参数的值使用以盐为密钥的 HMAC，没有盐的旧锁文件仍然使用 sha256
+func secretHash(salt, s string) string {
+	if salt == "" {
+		return hashOf(s)
+	}
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(s))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// 云服务器元数据的来源为 provider:key，不是文件
+func isCloudMetadataKey(key string) bool {
+	p, _, ok := strings.Cut(key, ":")
+	return ok && (p == "aws" || p == "gcp" || p == "azure")
+}
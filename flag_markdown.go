@@ -0,0 +1,66 @@
+package flags
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 生成 Markdown 格式的参数文档，包括参数、环境变量、配置项和默认值
+func GenMarkdown(w io.Writer, flags ...*FlagSet) (err error) {
+	set := flagSet(flags)
+	meta := metaOf(set)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "| Flag | Env | Config | Default | Description |\n")
+	fmt.Fprintf(&buf, "| ---- | --- | ------ | ------- | ----------- |\n")
+
+	set.VisitAll(func(f *Flag) {
+		if f.Hidden {
+			return
+		}
+
+		name := "`--" + f.Name + "`"
+		if f.Shorthand != "" {
+			name = "`-" + f.Shorthand + "`, " + name
+		}
+
+		var env, config string
+		usage := f.Usage
+		if field := meta.field(f.Name); field != nil {
+			if len(field.Env) > 0 {
+				env = "`" + strings.Join(field.Env, "`, `") + "`"
+			}
+			config = "`" + configKey(field) + "`"
+			if usage = field.Usage; usage == "" {
+				usage = field.Field.Name
+			}
+		}
+
+		var defVal string
+		if f.DefValue != "" && f.DefValue != "[]" {
+			defVal = "`" + f.DefValue + "`"
+			if isSecret(set, f) {
+				defVal = "`" + redacted + "`"
+			}
+		}
+
+		fmt.Fprintf(&buf, "| %s | %s | %s | %s | %s |\n", name, env, config, mdEscape(defVal), mdEscape(usage))
+	})
+
+	_, err = w.Write(buf.Bytes())
+	return
+}
+
+// 字段在配置文件中的键名，依次取 json、yaml、toml 标签，默认为字段名
+func configKey(field *FlagField) string {
+	for _, tagName := range []string{"json", "yaml", "toml"} {
+		if key, _, _ := strings.Cut(getTag(field.Field.Tag, tagName), ","); key != "" && key != "-" {
+			return key
+		}
+	}
+	return field.Field.Name
+}
+
+var mdEscape = strings.NewReplacer("|", "\\|", "\n", "<br>").Replace
@@ -0,0 +1,202 @@
+package flags
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/pflag"
+)
+
+// 帮助信息中的标题和类型名，key 为英文原文，类型名的 key 为 `type.<类型>`，如 type.duration
+var messages = map[string]string{}
+
+// 中文的帮助信息
+var MessagesZH = map[string]string{
+	"USAGE":    "用法",
+	"OPTIONS":  "参数",
+	"EXAMPLES": "示例",
+	"GROUPS":   "参数组",
+	"PRESETS":  "预设",
+
+	"Version":     "版本",
+	"Flag":        "参数",
+	"Shorthand":   "短参数",
+	"Type":        "类型",
+	"Description": "说明",
+	"Default":     "默认值",
+	"Environment": "环境变量",
+	"Deprecated":  "已过期",
+
+	"type.string":   "字符串",
+	"type.strings":  "字符串列表",
+	"type.int":      "整数",
+	"type.ints":     "整数列表",
+	"type.uint":     "正整数",
+	"type.float":    "小数",
+	"type.duration": "时长",
+	"type.time":     "时间",
+	"type.ip":       "IP",
+	"type.url":      "网址",
+	"type.path":     "路径",
+	"type.size":     "大小",
+	"type.json":     "JSON",
+}
+
+// 替换帮助信息中的文字，合并到已有的设置中，如 SetMessages(MessagesZH)
+func SetMessages(m map[string]string) {
+	for k, v := range m {
+		messages[k] = v
+	}
+}
+
+// 设置帮助信息中类型的显示名称，如 TypeName("duration", "时长")
+//
+// bool 和 count 类型不显示类型名，不能替换
+func TypeName(typ, name string) { messages["type."+typ] = name }
+
+func msg(key string) string {
+	if s, ok := messages[key]; ok && s != "" {
+		return s
+	}
+	return key
+}
+
+// 用于输出帮助信息的参数集合，复制每个参数，敏感参数的默认值显示为 ******，类型替换为设置的名称
+//
+// 不修改原来的参数，输出帮助信息时其他 goroutine 可以同时读取
+func usageSet(set *FlagSet) *FlagSet {
+	display := pflag.NewFlagSet("", pflag.ContinueOnError)
+	display.SortFlags = set.SortFlags
+	set.VisitAll(func(f *Flag) {
+		c := *f
+		if isSecret(set, f) {
+			c.DefValue = redactValue(f.DefValue)
+		}
+		if name, translated := typeName(f); translated {
+			c.Value = &typeNameValue{Value: f.Value, name: name, zero: isZeroDefault(f)}
+		}
+		display.AddFlag(&c)
+	})
+	return display
+}
+
+// 帮助信息中显示的类型名，同 pflag 的规则，bool 类型为空，translated 表示使用了设置的名称
+func typeName(f *Flag) (name string, translated bool) {
+	switch name = f.Value.Type(); name {
+	case "bool":
+		return "", false
+	case "count":
+		return name, false
+	case "float64", "int64", "uint64":
+		name = name[:len(name)-2]
+	case "stringSlice", "intSlice", "uintSlice", "boolSlice":
+		name = strings.TrimSuffix(name, "Slice") + "s"
+	}
+	if s, ok := messages["type."+name]; ok && s != "" {
+		return s, true
+	}
+	return name, false
+}
+
+// 只用于显示的包装，替换类型名，保持默认值是否为零值的判断
+type typeNameValue struct {
+	Value
+	name string
+	zero bool
+}
+
+func (v *typeNameValue) Type() string { return v.name }
+func (v *typeNameValue) String() string {
+	if v.zero {
+		return ""
+	}
+	return v.Value.String()
+}
+
+// 默认值是否为零值，零值不在帮助信息中显示
+func isZeroDefault(f *Flag) bool {
+	switch f.DefValue {
+	case "", "0", "0s", "false", "<nil>", "[]":
+		return true
+	}
+	return false
+}
+
+// 参数的帮助信息，pflag 按字节数对齐，类型名包含中文等宽字符时按显示宽度重新对齐
+func flagUsages(set *FlagSet) string {
+	s := set.FlagUsagesWrapped(0)
+	if len(messages) == 0 {
+		return s
+	}
+
+	// pflag 输出的每一行中说明都从第 maxlen+2 个字节开始，换行后的说明缩进同样的字节数
+	maxlen := 0
+	set.VisitAll(func(f *Flag) {
+		if n := len(usagePrefix(f)) + 1; !f.Hidden && n > maxlen {
+			maxlen = n
+		}
+	})
+	k := maxlen + 2
+
+	lines := strings.Split(s, "\n")
+	width := 0
+	for _, line := range lines {
+		if len(line) >= k {
+			if w := displayWidth(strings.TrimRight(line[:k], " ")); w > width {
+				width = w
+			}
+		}
+	}
+
+	for i, line := range lines {
+		if len(line) >= k {
+			head := strings.TrimRight(line[:k], " ")
+			lines[i] = head + strings.Repeat(" ", width+3-displayWidth(head)) + line[k:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 同 pflag 中每个参数说明之前的部分: 参数名、类型名和 NoOptDefVal
+func usagePrefix(f *Flag) (line string) {
+	if f.Shorthand != "" && f.ShorthandDeprecated == "" {
+		line = fmt.Sprintf("  -%s, --%s", f.Shorthand, f.Name)
+	} else {
+		line = fmt.Sprintf("      --%s", f.Name)
+	}
+
+	if varname, _ := pflag.UnquoteUsage(f); varname != "" {
+		line += " " + varname
+	}
+
+	if f.NoOptDefVal != "" {
+		switch f.Value.Type() {
+		case "string":
+			line += fmt.Sprintf("[=\"%s\"]", f.NoOptDefVal)
+		case "bool":
+			if f.NoOptDefVal != "true" {
+				line += fmt.Sprintf("[=%s]", f.NoOptDefVal)
+			}
+		case "count":
+			if f.NoOptDefVal != "+1" {
+				line += fmt.Sprintf("[=%s]", f.NoOptDefVal)
+			}
+		default:
+			line += fmt.Sprintf("[=%s]", f.NoOptDefVal)
+		}
+	}
+	return
+}
+
+// 终端中的显示宽度，中日韩文字和全角符号占两列
+func displayWidth(s string) (n int) {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana) || (r >= 0x3000 && r <= 0x303f) || (r >= 0xff01 && r <= 0xff60) {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return
+}
@@ -0,0 +1,106 @@
+package flags
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// 附加在 FlagSet 上的扩展信息
+type setMeta struct {
+	mu       sync.Mutex // 串行化绑定结构体和 SetAll 等批量写入
+	raw      map[string][]string
+	unknown  []string
+	fields   map[string]*FlagField // 绑定时持有 mu 写入，读取通过 field 和 fieldList
+	owners   map[string]string     // 参数所属的结构体字段
+	envUsers map[string][]string   // 环境变量名 => 使用的参数名
+	secrets  map[string]bool       // 通过 MarkSecret 标记的敏感参数，同 fields
+	prompts  map[string]string     // 没有值时提示输入的参数 => 提示信息
+	aliases  map[string]string     // 旧的参数名 => 新的参数名
+	examples []example
+	groups   []*flagGroup
+	structs  []reflect.Value
+	presets  []*preset
+	slices   []*structSlice
+	onParsed []func(set *FlagSet)
+	added    map[*FlagSet]bool // 通过 AddSet 加入的参数集合
+	watchers []watcher         // Watch* 注册的数据源，Refresh 时重新读取
+	onReload []func(ctx context.Context, changed map[string]string) error
+	stats    parseStats
+	ctx      context.Context // 由 metasMu 保护，见 contextOf
+
+	handling    pflag.ErrorHandling
+	hasHandling bool
+
+	name          string // New 创建的参数集合的程序名和版本号，为空时使用全局的设置
+	version       string
+	negatable     bool
+	dropShorthand bool // 短参数名冲突时去掉后注册的短参数名
+	noPrompt      bool // 不提示输入缺少的参数
+	inspect       bool // 只读模式，不读取环境变量
+	abbrev        bool // 允许长参数名缩写
+	normalize     NormalizeFunc
+	ignoreCase    bool   // 长参数名不区分大小写
+	downward      string // Downward API 文件挂载的目录
+	metadata      string // 云服务器元数据的提供者
+	conflict      conflictMode
+	nameCheck     func(name string) error
+}
+
+var (
+	metas   = map[*FlagSet]*setMeta{}
+	metasMu sync.Mutex
+)
+
+func metaOf(set *FlagSet) *setMeta {
+	metasMu.Lock()
+	defer metasMu.Unlock()
+	m, ok := metas[set]
+	if !ok {
+		m = &setMeta{fields: map[string]*FlagField{}, owners: map[string]string{}, envUsers: map[string][]string{}}
+		metas[set] = m
+	}
+	return m
+}
+
+// 绑定的字段，和绑定时一样持有 mu，可以在其他 goroutine 绑定时读取
+func (m *setMeta) field(name string) *FlagField {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fields[name]
+}
+
+// 所有绑定的字段的副本，同 field
+func (m *setMeta) fieldList() map[string]*FlagField {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fields := make(map[string]*FlagField, len(m.fields))
+	for name, field := range m.fields {
+		fields[name] = field
+	}
+	return fields
+}
+
+// 是否通过 MarkSecret 标记为敏感参数，同 field
+func (m *setMeta) markedSecret(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.secrets[name]
+}
+
+// 释放附加在参数集合上的扩展信息，不再使用时调用，全局的参数集合不需要释放
+func Release(flags ...*FlagSet) {
+	set := flagSet(flags)
+	metasMu.Lock()
+	defer metasMu.Unlock()
+	delete(metas, set)
+}
+
+// 已有的扩展信息，没有时返回 nil，不创建
+func lookupMeta(set *FlagSet) *setMeta {
+	metasMu.Lock()
+	defer metasMu.Unlock()
+	return metas[set]
+}
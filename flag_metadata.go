@@ -0,0 +1,204 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 云服务器元数据服务的地址，测试时可以替换
+var (
+	awsMetadataURL   = "http://169.254.169.254"
+	gcpMetadataURL   = "http://metadata.google.internal"
+	azureMetadataURL = "http://169.254.169.254"
+)
+
+// 每次请求元数据服务的超时时间
+var MetadataTimeout = time.Second
+
+// 开启云服务器元数据，provider 为 aws, gcp, azure 或者 auto(依次尝试)
+//
+// 开启后标记了 `metadata:"<key>"` 且没有通过其他方式设置的字段在解析完成后从元数据服务读取，
+// key 支持 region, zone, instance-id, instance-type, private-ip，读取的结果在进程内缓存
+func CloudMetadata(provider string, flags ...*FlagSet) { metaOf(flagSet(flags)).metadata = provider }
+
+var (
+	metadataCache = map[string]string{}
+	metadataLock  sync.Mutex
+)
+
+// 请求元数据服务的客户端，不使用 HTTP_PROXY 等代理，169.254.169.254 只能在本机访问
+var metadataClient = &http.Client{Transport: &http.Transport{Proxy: nil}}
+
+// 解析完成后从元数据服务读取默认值
+func applyMetadata(set *FlagSet) (err error) {
+	provider := metaOf(set).metadata
+	if provider == "" {
+		return
+	}
+	defer metaOf(set).stats.load(SourceMetadata, time.Now())
+
+	var fields []*FlagField
+	for _, field := range metaOf(set).fields {
+		if key := getTag(field.Field.Tag, _TAG_METADATA); key != "" && !field.Value.changed && field.Value.source == SourceDefault {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	// 每次解析只检测一次使用的云服务商，不在云服务器上时只输出一次警告，不再逐个字段等待超时
+	ctx := contextOf(set)
+	p, e := detectMetadata(ctx, provider)
+	if e != nil {
+		fmt.Fprintf(os.Stderr, "[WARN] 读取云服务器元数据失败: %v\n", e)
+		return
+	}
+
+	failed := map[string]error{}
+	for _, field := range fields {
+		key := getTag(field.Field.Tag, _TAG_METADATA)
+		if _, ok := failed[key]; ok {
+			continue
+		}
+
+		s, e := cloudMetadata(ctx, p, key)
+		if e != nil {
+			failed[key] = e
+			fmt.Fprintf(os.Stderr, "[WARN] 读取云服务器元数据[%s]失败: %v\n", key, e)
+			continue
+		}
+
+		if err = field.Value.SetDefault(s); err != nil {
+			return fmt.Errorf("invalid metadata %s=%q for flag --%s: %w", key, s, field.Name, err)
+		}
+		field.Value.setSource(SourceMetadata, p+":"+key)
+	}
+	return
+}
+
+// auto 时依次尝试读取 instance-id，返回可用的云服务商，检测成功的结果在进程内缓存
+func detectMetadata(ctx context.Context, provider string) (string, error) {
+	if provider != "auto" {
+		return provider, nil
+	}
+
+	metadataLock.Lock()
+	p, ok := metadataCache["auto"]
+	metadataLock.Unlock()
+	if ok {
+		return p, nil
+	}
+
+	var errs []string
+	for _, p := range []string{"aws", "gcp", "azure"} {
+		s, err := fetchMetadata(ctx, p, "instance-id")
+		if err != nil {
+			errs = append(errs, p+": "+err.Error())
+			continue
+		}
+
+		metadataLock.Lock()
+		metadataCache["auto"], metadataCache[p+":instance-id"] = p, s
+		metadataLock.Unlock()
+		return p, nil
+	}
+	return "", fmt.Errorf("no metadata service available (%s)", strings.Join(errs, "; "))
+}
+
+func cloudMetadata(ctx context.Context, provider, key string) (s string, err error) {
+	metadataLock.Lock()
+	defer metadataLock.Unlock()
+
+	if s, ok := metadataCache[provider+":"+key]; ok {
+		return s, nil
+	}
+	if s, err = fetchMetadata(ctx, provider, key); err == nil {
+		metadataCache[provider+":"+key] = s
+	}
+	return
+}
+
+func fetchMetadata(ctx context.Context, provider, key string) (s string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, MetadataTimeout)
+	defer cancel()
+
+	switch provider {
+	case "aws":
+		paths := map[string]string{
+			"region": "placement/region", "zone": "placement/availability-zone",
+			"instance-id": "instance-id", "instance-type": "instance-type", "private-ip": "local-ipv4",
+		}
+		if paths[key] == "" {
+			return "", fmt.Errorf("unsupported aws metadata: %s", key)
+		}
+
+		// IMDSv2
+		token, e := httpText(ctx, http.MethodPut, awsMetadataURL+"/latest/api/token", "X-aws-ec2-metadata-token-ttl-seconds", "60")
+		if e != nil {
+			return "", e
+		}
+		return httpText(ctx, http.MethodGet, awsMetadataURL+"/latest/meta-data/"+paths[key], "X-aws-ec2-metadata-token", token)
+	case "gcp":
+		paths := map[string]string{
+			"region": "zone", "zone": "zone",
+			"instance-id": "id", "instance-type": "machine-type", "private-ip": "network-interfaces/0/ip",
+		}
+		if paths[key] == "" {
+			return "", fmt.Errorf("unsupported gcp metadata: %s", key)
+		}
+		if s, err = httpText(ctx, http.MethodGet, gcpMetadataURL+"/computeMetadata/v1/instance/"+paths[key], "Metadata-Flavor", "Google"); err != nil {
+			return
+		}
+
+		// projects/123/zones/us-central1-a, projects/123/machineTypes/e2-small
+		if s = path.Base(s); key == "region" {
+			if i := strings.LastIndex(s, "-"); i > 0 {
+				s = s[:i]
+			}
+		}
+		return
+	case "azure":
+		paths := map[string]string{
+			"region": "compute/location", "zone": "compute/zone",
+			"instance-id": "compute/vmId", "instance-type": "compute/vmSize",
+			"private-ip": "network/interface/0/ipv4/ipAddress/0/privateIpAddress",
+		}
+		if paths[key] == "" {
+			return "", fmt.Errorf("unsupported azure metadata: %s", key)
+		}
+		return httpText(ctx, http.MethodGet, azureMetadataURL+"/metadata/instance/"+paths[key]+"?api-version=2021-02-01&format=text", "Metadata", "true")
+	default:
+		return "", fmt.Errorf("unknown metadata provider: %s", provider)
+	}
+}
+
+func httpText(ctx context.Context, method, url, header, value string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(header, value)
+
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: %s", method, url, resp.Status)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
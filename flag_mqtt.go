@@ -0,0 +1,398 @@
+package flags
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	MQTTRetryInterval = 5 * time.Second // 订阅断开后重新连接的间隔
+	MQTTWaitTimeout   = 5 * time.Second // 读取时等待保留消息的时间
+)
+
+const (
+	mqttMaxPacket = 16 << 20 // 报文长度的上限
+	mqttKeepAlive = 60       // 秒
+)
+
+// 从 MQTT 的保留消息读取配置，未定义的参数和来自命令行、环境变量的参数会忽略
+//
+// addr 为 mqtt://[user[:password]@]host[:port]，mqtts:// 或者 tlsConfig 不为 nil 时使用 TLS，
+// tlsConfig 可以设置 CA 和客户端证书。topic 不能包含通配符，保留消息为 JSON 或者 YAML 文档，
+// 嵌套的对象以 `.` 连接作为参数名。MQTTWaitTimeout 内没有收到保留消息时返回错误
+func LoadMQTT(ctx context.Context, addr, topic string, tlsConfig *tls.Config, flags ...*FlagSet) error {
+	set := flagSet(flags)
+	payload, err := fetchMQTT(ctx, addr, topic, tlsConfig)
+	if err != nil {
+		return err
+	}
+	values, err := decodeMQTT(topic, payload)
+	if err != nil {
+		return err
+	}
+	return SetAll(set, configValues(set, values), SourceConfig)
+}
+
+// 读取一次配置后订阅 topic，收到新的配置时写入变化的值，ctx 取消后停止
+//
+// 订阅断开后每隔 MQTTRetryInterval 重新连接，重新连接时服务端会再次发送保留消息。
+// 变化的值通过 SetAll 全部校验通过后才写入，写入后调用 OnReload 注册的函数。
+// 第一次读取失败时返回错误，之后解析、写入或者 OnReload 出错时调用 onError，onError 可以为 nil。
+// 也可以通过 Refresh 立即重新读取
+func WatchMQTT(ctx context.Context, addr, topic string, tlsConfig *tls.Config, onError func(error), flags ...*FlagSet) error {
+	set := flagSet(flags)
+	r := &remoteReload{set: set}
+
+	// 订阅收到的消息和 Refresh 读取的消息都写入 r
+	var mu sync.Mutex
+	apply := func(ctx context.Context, payload []byte) error {
+		values, err := decodeMQTT(topic, payload)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return r.apply(ctx, values)
+	}
+
+	refresh := addWatcher(ctx, set, func(ctx context.Context) error {
+		payload, err := fetchMQTT(ctx, addr, topic, tlsConfig)
+		if err != nil {
+			return err
+		}
+		return apply(ctx, payload)
+	})
+	if err := refresh(ctx); err != nil {
+		return err
+	}
+
+	report := func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+
+	go func() {
+		for {
+			report(subscribeMQTT(ctx, addr, topic, tlsConfig, func(payload []byte) { report(apply(ctx, payload)) }))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(MQTTRetryInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+func decodeMQTT(topic string, payload []byte) (map[string]string, error) {
+	// JSON 也是合法的 YAML
+	var v any
+	if err := yaml.Unmarshal(payload, &v); err != nil {
+		return nil, fmt.Errorf("invalid config in mqtt topic %q: %w", topic, err)
+	}
+	if _, ok := v.(map[string]any); !ok {
+		return nil, fmt.Errorf("config in mqtt topic %q must be a json or yaml object", topic)
+	}
+	values := map[string]string{}
+	flattenJSON("", v, values)
+	return values, nil
+}
+
+func fetchMQTT(ctx context.Context, addr, topic string, tlsConfig *tls.Config) ([]byte, error) {
+	conn, err := dialMQTT(ctx, addr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err = conn.subscribe(topic); err != nil {
+		return nil, err
+	}
+
+	conn.conn.SetReadDeadline(time.Now().Add(MQTTWaitTimeout))
+	for {
+		name, payload, retain, err := conn.next()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return nil, fmt.Errorf("mqtt: no retained message on topic %q", topic)
+			}
+			return nil, err
+		}
+		if name == topic && retain {
+			if len(payload) == 0 {
+				return nil, fmt.Errorf("mqtt: no retained message on topic %q", topic)
+			}
+			return payload, nil
+		}
+	}
+}
+
+// 订阅 topic，每次收到非空的消息时调用 apply，连接断开时返回错误，ctx 取消时返回 nil
+func subscribeMQTT(ctx context.Context, addr, topic string, tlsConfig *tls.Config, apply func([]byte)) error {
+	conn, err := dialMQTT(ctx, addr, tlsConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err = conn.subscribe(topic); err != nil {
+		return err
+	}
+
+	// 保持连接，服务端超过 1.5 倍 keep alive 没有收到报文时断开
+	go func() {
+		ticker := time.NewTicker(mqttKeepAlive * time.Second / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-conn.stop:
+				return
+			case <-ticker.C:
+				conn.conn.Write([]byte{0xc0, 0})
+			}
+		}
+	}()
+
+	for {
+		name, payload, _, err := conn.next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("mqtt subscribe %s: %w", topic, err)
+		}
+		// 空的保留消息表示删除，不作为配置
+		if name == topic && len(payload) > 0 {
+			apply(payload)
+		}
+	}
+}
+
+// 最简单的 MQTT 3.1.1 客户端，只用于订阅配置，QoS 0，不引入第三方依赖
+type mqttConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	stop chan struct{}
+}
+
+func dialMQTT(ctx context.Context, addr string, tlsConfig *tls.Config) (*mqttConn, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "mqtt" && u.Scheme != "mqtts") || u.Hostname() == "" {
+		return nil, fmt.Errorf("mqtt url must be mqtt[s]://[user[:password]@]host[:port]; got %s", addr)
+	}
+
+	useTLS := u.Scheme == "mqtts" || tlsConfig != nil
+	host := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if useTLS {
+			port = "8883"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	if useTLS {
+		cfg := &tls.Config{}
+		if tlsConfig != nil {
+			cfg = tlsConfig.Clone()
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = u.Hostname()
+		}
+		tc := tls.Client(conn, cfg)
+		if err = tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+
+	// ctx 取消时关闭连接，让阻塞的读取返回
+	c := &mqttConn{conn: conn, r: bufio.NewReader(conn), stop: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-c.stop:
+		}
+	}()
+
+	if err = c.connect(u.User); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+var mqttConnectErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+func (c *mqttConn) connect(user *url.Userinfo) error {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	var flags byte = 0x02 // clean session
+	payload := mqttString("flags-" + hex.EncodeToString(id))
+	if user != nil {
+		flags |= 0x80
+		payload = append(payload, mqttString(user.Username())...)
+		if password, ok := user.Password(); ok {
+			flags |= 0x40
+			payload = append(payload, mqttString(password)...)
+		}
+	}
+
+	body := append(mqttString("MQTT"), 4, flags, 0, mqttKeepAlive)
+	if err := c.write(0x10, append(body, payload...)); err != nil {
+		return err
+	}
+
+	typ, body, err := c.read()
+	if err != nil {
+		return err
+	}
+	if typ != 0x20 || len(body) != 2 {
+		return fmt.Errorf("mqtt: unexpected reply to connect")
+	}
+	if rc := body[1]; rc != 0 {
+		if msg, ok := mqttConnectErrors[rc]; ok {
+			return fmt.Errorf("mqtt: connection refused: %s", msg)
+		}
+		return fmt.Errorf("mqtt: connection refused: code %d", rc)
+	}
+	return nil
+}
+
+func (c *mqttConn) subscribe(topic string) error {
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		return fmt.Errorf("mqtt topic must not be empty or contain wildcards; got %q", topic)
+	}
+	// packet id 1，QoS 0
+	if err := c.write(0x82, append(append([]byte{0, 1}, mqttString(topic)...), 0)); err != nil {
+		return err
+	}
+	for {
+		typ, body, err := c.read()
+		if err != nil {
+			return err
+		}
+		if typ == 0x90 {
+			if len(body) != 3 || body[2] == 0x80 {
+				return fmt.Errorf("mqtt: subscribe %s rejected", topic)
+			}
+			return nil
+		}
+	}
+}
+
+func (c *mqttConn) Close() error {
+	close(c.stop)
+	c.conn.Write([]byte{0xe0, 0})
+	return c.conn.Close()
+}
+
+// 读取下一个 PUBLISH 报文，其它报文忽略
+func (c *mqttConn) next() (topic string, payload []byte, retain bool, err error) {
+	for {
+		var typ byte
+		var body []byte
+		if typ, body, err = c.read(); err != nil {
+			return
+		}
+		if typ&0xf0 != 0x30 {
+			continue
+		}
+		if len(body) < 2 {
+			return "", nil, false, fmt.Errorf("mqtt: invalid publish packet")
+		}
+		n := int(binary.BigEndian.Uint16(body)) + 2
+		if qos := typ >> 1 & 3; qos > 0 {
+			n += 2
+		}
+		if n > len(body) {
+			return "", nil, false, fmt.Errorf("mqtt: invalid publish packet")
+		}
+		return string(body[2 : 2+binary.BigEndian.Uint16(body)]), body[n:], typ&1 == 1, nil
+	}
+}
+
+func (c *mqttConn) read() (typ byte, body []byte, err error) {
+	if typ, err = c.r.ReadByte(); err != nil {
+		return
+	}
+
+	// 剩余长度最多 4 个字节，每个字节低 7 位有效
+	var n int
+	for i := 0; ; i++ {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, fmt.Errorf("mqtt: invalid remaining length")
+		}
+	}
+	if n > mqttMaxPacket {
+		return 0, nil, fmt.Errorf("mqtt: packet of %d bytes exceeds %d", n, mqttMaxPacket)
+	}
+
+	body = make([]byte, n)
+	_, err = io.ReadFull(c.r, body)
+	return
+}
+
+func (c *mqttConn) write(typ byte, body []byte) error {
+	buf := []byte{typ}
+	n := len(body)
+	for {
+		b := byte(n & 0x7f)
+		if n >>= 7; n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := c.conn.Write(append(buf, body...))
+	return err
+}
+
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
@@ -0,0 +1,41 @@
+package flags
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 设置参数名检查，之后注册的参数名不符合要求时注册失败
+//
+// 通常只在开发构建中开启，尽早发现不一致的命名
+func NameConvention(set *FlagSet, check func(name string) error) {
+	metaOf(set).nameCheck = check
+}
+
+var kebabCase = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*(\.[a-z0-9]+(-[a-z0-9]+)*)*$`)
+
+// kebab-case 命名检查，嵌套的参数名以 `.` 分隔，maxLen 为 0 时不限制长度，reservedPrefixes 为保留的前缀
+func KebabCase(maxLen int, reservedPrefixes ...string) func(name string) error {
+	return func(name string) error {
+		if !kebabCase.MatchString(name) {
+			return fmt.Errorf("flag name %q is not kebab-case", name)
+		}
+		if maxLen > 0 && len(name) > maxLen {
+			return fmt.Errorf("flag name %q is longer than %d", name, maxLen)
+		}
+		for _, prefix := range reservedPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return fmt.Errorf("flag name %q uses reserved prefix %q", name, prefix)
+			}
+		}
+		return nil
+	}
+}
+
+func checkName(set *FlagSet, name string) error {
+	if check := metaOf(set).nameCheck; check != nil {
+		return check(name)
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package flags
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 订阅断开后重新连接的间隔
+var NATSRetryInterval = 5 * time.Second
+
+// 消息长度的上限，超过时返回错误，避免按服务端给出的长度分配过多内存
+const natsMaxPayload = 64 << 20
+
+var (
+	natsBucket = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	natsKey    = regexp.MustCompile(`^[-/_=a-zA-Z0-9]+(\.[-/_=a-zA-Z0-9]+)*$`)
+)
+
+// 从 NATS KV 的 bucket 读取配置，key 为参数名，值为参数的值，未定义的参数和来自命令行、环境变量的参数会忽略
+//
+// addr 为 nats://[user:password@|token@]host[:port]，tls:// 或者服务端要求时使用 TLS。
+// 只读取已经定义的参数对应的 key，已删除的 key 和名称不能作为 key 的参数忽略
+func LoadNATS(ctx context.Context, addr, bucket string, flags ...*FlagSet) error {
+	set := flagSet(flags)
+	values, err := fetchNATS(ctx, addr, bucket, set)
+	if err != nil {
+		return err
+	}
+	return SetAll(set, configValues(set, values), SourceConfig)
+}
+
+// 读取一次配置后订阅 bucket 的变化，有 key 变化时重新读取并写入变化的值，ctx 取消后停止
+//
+// 订阅断开后每隔 NATSRetryInterval 重新连接，连接后重新读取一次。
+// 变化的值通过 SetAll 全部校验通过后才写入，写入后调用 OnReload 注册的函数。
+// 第一次读取失败时返回错误，之后读取、写入或者 OnReload 出错时调用 onError，onError 可以为 nil。
+// 也可以通过 Refresh 立即重新读取
+func WatchNATS(ctx context.Context, addr, bucket string, onError func(error), flags ...*FlagSet) error {
+	set := flagSet(flags)
+	r := &remoteReload{set: set}
+	refresh := addWatcher(ctx, set, func(ctx context.Context) error {
+		values, err := fetchNATS(ctx, addr, bucket, set)
+		if err != nil {
+			return err
+		}
+		return r.apply(ctx, values)
+	})
+	if err := refresh(ctx); err != nil {
+		return err
+	}
+
+	report := func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+
+	go func() {
+		for {
+			report(subscribeNATS(ctx, addr, bucket, func() { report(refresh(ctx)) }))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(NATSRetryInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+func fetchNATS(ctx context.Context, addr, bucket string, set *FlagSet) (values map[string]string, err error) {
+	if !natsBucket.MatchString(bucket) {
+		return nil, fmt.Errorf("invalid nats kv bucket %q", bucket)
+	}
+
+	var names []string
+	set.VisitAll(func(f *Flag) {
+		if natsKey.MatchString(f.Name) {
+			names = append(names, f.Name)
+		}
+	})
+
+	conn, err := dialNATS(ctx, addr)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if err = conn.write("SUB " + conn.inbox + " 1\r\n"); err != nil {
+		return
+	}
+
+	values = map[string]string{}
+	for _, name := range names {
+		req, _ := json.Marshal(map[string]string{"last_by_subj": "$KV." + bucket + "." + name})
+		msg, err := conn.request("$JS.API.STREAM.MSG.GET.KV_"+bucket, req)
+		if err != nil {
+			return nil, err
+		}
+		if status := natsStatus(msg.hdr); status == "503" {
+			return nil, fmt.Errorf("nats: no responders for kv bucket %q, jetstream not enabled?", bucket)
+		}
+
+		var resp struct {
+			Message struct {
+				Hdrs []byte `json:"hdrs"`
+				Data []byte `json:"data"`
+			} `json:"message"`
+			Error *struct {
+				ErrCode     int    `json:"err_code"`
+				Description string `json:"description"`
+			} `json:"error"`
+		}
+		if err = json.Unmarshal(msg.data, &resp); err != nil {
+			return nil, fmt.Errorf("nats kv %s: invalid response: %w", bucket, err)
+		}
+		if resp.Error != nil {
+			// 10037: no message found
+			if resp.Error.ErrCode == 10037 {
+				continue
+			}
+			return nil, fmt.Errorf("nats kv %s: %s", bucket, resp.Error.Description)
+		}
+		if op := natsHeader(resp.Message.Hdrs, "KV-Operation"); op == "DEL" || op == "PURGE" {
+			continue
+		}
+		values[name] = string(resp.Message.Data)
+	}
+	return
+}
+
+// 订阅 bucket 的所有 key，订阅成功后和每次收到变化时调用 refresh，连接断开时返回错误，ctx 取消时返回 nil
+func subscribeNATS(ctx context.Context, addr, bucket string, refresh func()) error {
+	conn, err := dialNATS(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err = conn.write("SUB $KV." + bucket + ".> 1\r\nPING\r\n"); err != nil {
+		return err
+	}
+	if err = conn.pong(); err != nil {
+		return err
+	}
+	// 断开期间的变化没有收到通知
+	refresh()
+
+	for {
+		op, err := conn.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("nats subscribe %s: %w", bucket, err)
+		}
+		if op.kind == "MSG" {
+			refresh()
+		}
+	}
+}
+
+// 最简单的 NATS 协议客户端，只用于读取 KV 和订阅，不引入第三方依赖
+type natsConn struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	stop  chan struct{}
+	inbox string
+}
+
+type natsOp struct {
+	kind    string // MSG(包括 HMSG)、PONG
+	subject string
+	hdr     []byte
+	data    []byte
+}
+
+func dialNATS(ctx context.Context, addr string) (*natsConn, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "nats" && u.Scheme != "tls") || u.Hostname() == "" {
+		return nil, fmt.Errorf("nats url must be nats://[user:password@|token@]host[:port]; got %s", addr)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	// ctx 取消时关闭连接，让阻塞的读取返回
+	c := &natsConn{conn: conn, r: bufio.NewReader(conn), stop: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-c.stop:
+		}
+	}()
+
+	if err = c.handshake(ctx, u); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	c.inbox = "_INBOX." + hex.EncodeToString(id)
+	return c, nil
+}
+
+func (c *natsConn) handshake(ctx context.Context, u *url.URL) error {
+	line, err := c.line()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("nats: unexpected greeting %q", line)
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	if err = json.Unmarshal([]byte(line[5:]), &info); err != nil {
+		return fmt.Errorf("nats: invalid info: %w", err)
+	}
+
+	if u.Scheme == "tls" || info.TLSRequired {
+		tc := tls.Client(c.conn, &tls.Config{ServerName: u.Hostname()})
+		if err = tc.HandshakeContext(ctx); err != nil {
+			return err
+		}
+		c.conn, c.r = tc, bufio.NewReader(tc)
+	}
+
+	opts := map[string]any{"verbose": false, "pedantic": false, "lang": "go", "name": "flags", "protocol": 1, "headers": true, "no_responders": true}
+	if password, ok := u.User.Password(); ok {
+		opts["user"], opts["pass"] = u.User.Username(), password
+	} else if token := u.User.Username(); token != "" {
+		opts["auth_token"] = token
+	}
+	connect, _ := json.Marshal(opts)
+	if err = c.write("CONNECT " + string(connect) + "\r\nPING\r\n"); err != nil {
+		return err
+	}
+	return c.pong()
+}
+
+func (c *natsConn) Close() error {
+	close(c.stop)
+	return c.conn.Close()
+}
+
+func (c *natsConn) write(s string) error {
+	_, err := io.WriteString(c.conn, s)
+	return err
+}
+
+// 等待 PING 的回复，之前收到的消息忽略
+func (c *natsConn) pong() error {
+	for {
+		op, err := c.read()
+		if err != nil {
+			return err
+		}
+		if op.kind == "PONG" {
+			return nil
+		}
+	}
+}
+
+// 发布请求并等待 inbox 的回复，需要先订阅 inbox
+func (c *natsConn) request(subject string, payload []byte) (natsOp, error) {
+	if err := c.write(fmt.Sprintf("PUB %s %s %d\r\n%s\r\n", subject, c.inbox, len(payload), payload)); err != nil {
+		return natsOp{}, err
+	}
+	for {
+		op, err := c.read()
+		if err != nil {
+			return op, err
+		}
+		if op.kind == "MSG" && op.subject == c.inbox {
+			return op, nil
+		}
+	}
+}
+
+func (c *natsConn) line() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+// 读取下一个消息或者 PONG，服务端的 PING 直接回复
+func (c *natsConn) read() (natsOp, error) {
+	for {
+		line, err := c.line()
+		if err != nil {
+			return natsOp{}, err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			if err = c.write("PONG\r\n"); err != nil {
+				return natsOp{}, err
+			}
+		case "PONG":
+			return natsOp{kind: "PONG"}, nil
+		case "-ERR":
+			return natsOp{}, fmt.Errorf("nats: %s", strings.Trim(strings.TrimSpace(line[4:]), "'"))
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			if len(fields) < 4 {
+				return natsOp{}, fmt.Errorf("nats: invalid message %q", line)
+			}
+			data, err := c.payload(fields[len(fields)-1])
+			return natsOp{kind: "MSG", subject: fields[1], data: data}, err
+		case "HMSG":
+			// HMSG <subject> <sid> [reply-to] <#header bytes> <#total bytes>
+			if len(fields) < 5 {
+				return natsOp{}, fmt.Errorf("nats: invalid message %q", line)
+			}
+			n, err := strconv.Atoi(fields[len(fields)-2])
+			if err != nil || n < 0 {
+				return natsOp{}, fmt.Errorf("nats: invalid message %q", line)
+			}
+			data, err := c.payload(fields[len(fields)-1])
+			if err != nil {
+				return natsOp{}, err
+			}
+			if n > len(data) {
+				return natsOp{}, fmt.Errorf("nats: invalid message %q", line)
+			}
+			return natsOp{kind: "MSG", subject: fields[1], hdr: data[:n], data: data[n:]}, nil
+		}
+		// +OK、INFO 忽略
+	}
+}
+
+func (c *natsConn) payload(size string) ([]byte, error) {
+	n, err := strconv.Atoi(size)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("nats: invalid message size %q", size)
+	}
+	if n > natsMaxPayload {
+		return nil, fmt.Errorf("nats: message of %d bytes exceeds %d", n, natsMaxPayload)
+	}
+	buf := make([]byte, n+2)
+	if _, err = io.ReadFull(c.r, buf); err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// 消息头第一行 NATS/1.0 之后的状态码
+func natsStatus(hdr []byte) string {
+	line, _, _ := bytes.Cut(hdr, []byte("\r\n"))
+	if fields := strings.Fields(string(line)); len(fields) > 1 {
+		return fields[1]
+	}
+	return ""
+}
+
+func natsHeader(hdr []byte, key string) string {
+	for _, line := range strings.Split(string(hdr), "\r\n")[1:] {
+		if k, v, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package flags
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 参数名的规范化，规范化后相同的参数名视为同一个参数
+type NormalizeFunc func(name string) string
+
+// 默认的规范化，`--max_conn` 等同于 `--max-conn`
+func DashUnderscore(name string) string { return strings.ReplaceAll(name, "_", "-") }
+
+// 设置命令行参数名的规范化，规范化后和注册的参数名相同时替换为注册的参数名，类似 pflag 的 SetNormalizeFunc
+//
+// 默认使用 DashUnderscore，fn 为 nil 时关闭。只影响命令行，不修改注册的参数名
+func NormalizeNames(fn NormalizeFunc, flags ...*FlagSet) {
+	if fn == nil {
+		fn = func(name string) string { return name }
+	}
+	metaOf(flagSet(flags)).normalize = fn
+}
+
+// 长参数名不区分大小写，`--Port`、`--PORT` 都等同于 `--port`，方便习惯 Windows 命令行的用户
+//
+// 和 NormalizeNames 设置的规范化同时生效，只影响长参数名，短参数名仍然区分大小写
+func IgnoreCase(flags ...*FlagSet) { metaOf(flagSet(flags)).ignoreCase = true }
+
+func normalizeOf(meta *setMeta) NormalizeFunc {
+	fn := meta.normalize
+	if fn == nil {
+		fn = DashUnderscore
+	}
+	if meta.ignoreCase {
+		return func(name string) string { return strings.ToLower(fn(name)) }
+	}
+	return fn
+}
+
+// 按规范化后的参数名查找，没有找到时返回空，找到多个时返回错误
+func matchNormalized(set *FlagSet, fn NormalizeFunc, name string) (string, error) {
+	want := fn(name)
+	var candidates []string
+	set.VisitAll(func(f *Flag) {
+		if fn(f.Name) == want {
+			candidates = append(candidates, f.Name)
+		}
+	})
+
+	switch len(candidates) {
+	case 0:
+		return "", nil
+	case 1:
+		return candidates[0], nil
+	default:
+		sort.Strings(candidates)
+		return "", fmt.Errorf("option '--%s' is ambiguous; possibilities: --%s", name, strings.Join(candidates, " --"))
+	}
+}
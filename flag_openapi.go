@@ -0,0 +1,161 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]any `json:"paths" yaml:"paths"`
+	Components struct {
+		Parameters map[string]openAPIParam `json:"parameters" yaml:"parameters"`
+	} `json:"components" yaml:"components"`
+	Parameters map[string]openAPIParam `json:"parameters" yaml:"parameters"` // swagger 2.0
+}
+
+type openAPIParam struct {
+	Ref           string         `json:"$ref" yaml:"$ref"`
+	Name          string         `json:"name" yaml:"name"`
+	In            string         `json:"in" yaml:"in"`
+	Description   string         `json:"description" yaml:"description"`
+	Required      bool           `json:"required" yaml:"required"`
+	Schema        *openAPISchema `json:"schema" yaml:"schema"`
+	openAPISchema `yaml:",inline"`
+}
+
+type openAPISchema struct {
+	Type    string         `json:"type" yaml:"type"`
+	Format  string         `json:"format" yaml:"format"`
+	Enum    []any          `json:"enum" yaml:"enum"`
+	Default any            `json:"default" yaml:"default"`
+	Minimum *float64       `json:"minimum" yaml:"minimum"`
+	Maximum *float64       `json:"maximum" yaml:"maximum"`
+	Items   *openAPISchema `json:"items" yaml:"items"`
+}
+
+// 将 OpenAPI(或 Swagger 2.0) 文档中指定 operationId 的参数转换为参数定义，可以通过 SpecBind 注册
+//
+// 参数名、类型、枚举值、取值范围、默认值和是否必填都会被转换，body 参数会被忽略
+func OpenAPISpec(contentType string, data []byte, operationID string) (spec *Spec, err error) {
+	var doc openAPIDoc
+	if err = LoadConfig(&doc, contentType, data); err != nil {
+		return
+	}
+
+	for _, item := range doc.Paths {
+		for method, op := range item {
+			if method == "parameters" {
+				continue
+			}
+			opMap, _ := op.(map[string]any)
+			if id, _ := opMap["operationId"].(string); id != operationID {
+				continue
+			}
+
+			var params []openAPIParam
+			for _, raw := range []any{item["parameters"], opMap["parameters"]} {
+				var list []openAPIParam
+				if raw != nil {
+					if err = convertTo(raw, &list); err != nil {
+						return
+					}
+				}
+				params = append(params, list...)
+			}
+			return doc.spec(params)
+		}
+	}
+	return nil, fmt.Errorf("operation %q not found", operationID)
+}
+
+func (doc *openAPIDoc) spec(params []openAPIParam) (spec *Spec, err error) {
+	spec = &Spec{}
+	for _, p := range params {
+		if p.Ref != "" {
+			name := p.Ref[strings.LastIndex(p.Ref, "/")+1:]
+			ref, ok := doc.Components.Parameters[name]
+			if !ok {
+				ref, ok = doc.Parameters[name]
+			}
+			if !ok {
+				return nil, fmt.Errorf("parameter %s not found", p.Ref)
+			}
+			p = ref
+		}
+
+		if p.In == "body" || p.Name == "" {
+			continue
+		}
+
+		schema := p.openAPISchema
+		if p.Schema != nil {
+			schema = *p.Schema
+		}
+
+		fs := FlagSpec{Name: p.Name, Usage: p.Description, Required: p.Required, Default: schema.Default}
+		if fs.Type, err = openAPIType(schema); err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", p.Name, err)
+		}
+
+		// 数组的校验规则作用于元素
+		rs := schema
+		if rs.Type == "array" && rs.Items != nil {
+			rs = *rs.Items
+		}
+
+		var rules []string
+		if rs.Minimum != nil {
+			rules = append(rules, "min="+strconv.FormatFloat(*rs.Minimum, 'f', -1, 64))
+		}
+		if rs.Maximum != nil {
+			rules = append(rules, "max="+strconv.FormatFloat(*rs.Maximum, 'f', -1, 64))
+		}
+		if len(rs.Enum) > 0 {
+			rules = append(rules, "oneof="+strings.Join(specDefault(rs.Enum), " "))
+		}
+		fs.Validate = strings.Join(rules, ",")
+
+		spec.Flags = append(spec.Flags, fs)
+	}
+	return
+}
+
+func openAPIType(schema openAPISchema) (string, error) {
+	switch schema.Type {
+	case "", "string":
+		if schema.Format == "date-time" || schema.Format == "date" {
+			return "time", nil
+		}
+		return "string", nil
+	case "integer":
+		if schema.Format == "int32" {
+			return "int32", nil
+		}
+		return "int64", nil
+	case "number":
+		if schema.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		if schema.Items == nil {
+			return "strings", nil
+		}
+		t, err := openAPIType(*schema.Items)
+		return t + "s", err
+	default:
+		return "", fmt.Errorf("unsupported type: %s", schema.Type)
+	}
+}
+
+func convertTo(in, out any) error {
+	data, err := json.Marshal(in)
+	if err == nil {
+		err = json.Unmarshal(data, out)
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 已注册参数的环境变量冲突，键为环境变量名，值为使用该环境变量的参数名
+func EnvCollisions(flags ...*FlagSet) map[string][]string {
+	collisions := map[string][]string{}
+	for key, names := range metaOf(flagSet(flags)).envUsers {
+		if len(names) > 1 {
+			names = append([]string(nil), names...)
+			sort.Strings(names)
+			collisions[key] = names
+		}
+	}
+	return collisions
+}
+
+// 输出每个模块(绑定的结构体)拥有的参数和环境变量，以及环境变量的冲突
+func OwnerReport(w io.Writer, flags ...*FlagSet) (err error) {
+	set := flagSet(flags)
+	meta := metaOf(set)
+
+	var owners []string
+	byOwner := map[string][]string{}
+	set.VisitAll(func(f *Flag) {
+		owner := meta.owners[f.Name]
+		if owner == "" {
+			owner = "-"
+		}
+		if _, ok := byOwner[owner]; !ok {
+			owners = append(owners, owner)
+		}
+
+		line := "--" + f.Name
+		if field := meta.field(f.Name); field != nil && len(field.Env) > 0 {
+			line += " (env: " + strings.Join(field.Env, ", ") + ")"
+		}
+		byOwner[owner] = append(byOwner[owner], line)
+	})
+
+	var sb strings.Builder
+	for _, owner := range owners {
+		fmt.Fprintf(&sb, "%s\n", owner)
+		for _, line := range byOwner[owner] {
+			fmt.Fprintf(&sb, "      %s\n", line)
+		}
+	}
+
+	if collisions := EnvCollisions(set); len(collisions) > 0 {
+		keys := make([]string, 0, len(collisions))
+		for key := range collisions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(&sb, "\nENV COLLISIONS:\n")
+		for _, key := range keys {
+			var users []string
+			for _, name := range collisions[key] {
+				users = append(users, fmt.Sprintf("--%s (%s)", name, meta.owners[name]))
+			}
+			fmt.Fprintf(&sb, "      %s: %s\n", key, strings.Join(users, ", "))
+		}
+	}
+
+	_, err = io.WriteString(w, sb.String())
+	return
+}
+
+// 去掉过期标记 `*` 后的环境变量名
+func envKeys(field *FlagField) (keys []string) {
+	for _, key := range field.Env {
+		if key = strings.TrimPrefix(key, "*"); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
+// 注册时检查环境变量是否已经被其他参数使用，并记录到环境变量索引中
+func warnEnvCollision(set *FlagSet, field *FlagField, owner string) {
+	meta := metaOf(set)
+	for _, key := range envKeys(field) {
+		for _, name := range meta.envUsers[key] {
+			fmt.Fprintf(os.Stderr, "[WARN] 环境变量[%s]同时被参数 --%s(%s) 和 --%s(%s) 使用\n", key, name, meta.owners[name], field.Name, owner)
+		}
+		meta.envUsers[key] = append(meta.envUsers[key], field.Name)
+	}
+}
+
+// 多个模块使用同一个短参数名时，后注册的去掉短参数名并输出警告，而不是注册失败
+func DropShorthandConflicts(flags ...*FlagSet) {
+	metaOf(flagSet(flags)).dropShorthand = true
+}
+
+// 注册前检查参数名是否已经被使用，pflag 在重复注册时直接 panic，这里提前返回带有双方所属字段的错误
+func checkDuplicate(set *FlagSet, name, owner string) error {
+	meta := metaOf(set)
+	if f := set.Lookup(name); f != nil {
+		if old := meta.owners[f.Name]; old != "" {
+			return fmt.Errorf("duplicate flag --%s: defined by both %s and %s", name, old, owner)
+		}
+		return fmt.Errorf("duplicate flag --%s: %s conflicts with an existing flag", name, owner)
+	}
+	return nil
+}
+
+// 检查短参数名是否已经被使用，开启 DropShorthandConflicts 时去掉 field 的短参数名
+func checkShorthand(set *FlagSet, field *FlagField, owner string) error {
+	if field.Shorthand == "" {
+		return nil
+	}
+	if f := set.ShorthandLookup(field.Shorthand); f != nil {
+		return shorthandConflict(set, field, f.Name, ownerOf(metaOf(set), f.Name), owner)
+	}
+	return nil
+}
+
+func shorthandConflict(set *FlagSet, field *FlagField, oldName, oldOwner, owner string) error {
+	if !metaOf(set).dropShorthand {
+		return fmt.Errorf("duplicate shorthand -%s: used by both --%s (%s) and --%s (%s)", field.Shorthand, oldName, oldOwner, field.Name, owner)
+	}
+	warnShorthandDropped(field.Shorthand, oldName, oldOwner, field.Name, owner)
+	field.Shorthand = ""
+	return nil
+}
+
+// 绑定结构体之前检查所有字段的参数名、取消参数名、别名和短参数名，有任何冲突时不注册任何参数
+func checkFields(set *FlagSet, fields []*FlagField, owner func(*FlagField) string) error {
+	names := map[string]string{}     // 本次绑定的参数名 => 所属字段
+	shorts := map[string][2]string{} // 本次绑定的短参数名 => 参数名和所属字段
+	for _, field := range fields {
+		o := owner(field)
+		if err := checkPriority(field); err != nil {
+			return err
+		}
+		if err := checkEncoding(field); err != nil {
+			return err
+		}
+
+		all := append([]string{field.Name}, field.Aliases...)
+		if isNegatable(set, field) {
+			all = append(all, "no-"+field.Name)
+		}
+		for _, name := range all {
+			if err := checkName(set, name); err != nil {
+				return err
+			}
+			if err := checkDuplicate(set, name, o); err != nil {
+				return err
+			}
+			if old, ok := names[name]; ok {
+				return fmt.Errorf("duplicate flag --%s: defined by both %s and %s", name, old, o)
+			}
+			names[name] = o
+		}
+
+		if err := checkShorthand(set, field, o); err != nil {
+			return err
+		}
+		if field.Shorthand == "" {
+			continue
+		}
+		if old, ok := shorts[field.Shorthand]; ok {
+			if err := shorthandConflict(set, field, old[0], old[1], o); err != nil {
+				return err
+			}
+			continue
+		}
+		shorts[field.Shorthand] = [2]string{field.Name, o}
+	}
+	return nil
+}
+
+func warnShorthandDropped(shorthand, oldName, oldOwner, name, owner string) {
+	fmt.Fprintf(os.Stderr, "[WARN] 短参数名[-%s]已被 --%s(%s) 使用，--%s(%s) 不再使用短参数名\n", shorthand, oldName, oldOwner, name, owner)
+}
@@ -0,0 +1,50 @@
+package flags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 只解析 names 中的参数，其他参数和未定义的参数都会忽略，用于在定义完整的参数前先取得 --config, --profile 等参数
+//
+// 解析后参数不会标记为已设置，之后完整解析时仍然可以再次设置
+func ParsePartial(set *FlagSet, args []string, names ...string) (err error) {
+	for _, t := range scanArgs(set, args) {
+		if t.Flag == nil || !sliceContains(names, t.Flag.Name) {
+			continue
+		}
+
+		if err = set.Set(t.Flag.Name, t.value()); err != nil {
+			return fmt.Errorf("invalid argument %q for %q flag: %w", strings.Join(t.Raw, " "), "--"+t.Flag.Name, err)
+		}
+
+		t.Flag.Changed = false
+		if v, ok := t.Flag.Value.(*value); ok {
+			v.changed = false
+		}
+	}
+	return
+}
+
+// 取出参数的值: --name=v, --name v, -nv, -n=v, -n v，没有值时使用 NoOptDefVal
+func (t rawToken) value() string {
+	if len(t.Raw) == 2 {
+		return t.Raw[1]
+	}
+
+	s := t.Raw[0]
+	if strings.HasPrefix(s, "--") {
+		if _, v, ok := strings.Cut(s, "="); ok {
+			return v
+		}
+		return t.Flag.NoOptDefVal
+	}
+
+	// 合并的短参数中，值跟在自身后面
+	if i := strings.Index(s[1:], t.Name); i >= 0 {
+		if v := strings.TrimPrefix(s[i+2:], "="); v != "" && t.Flag.NoOptDefVal == "" {
+			return v
+		}
+	}
+	return t.Flag.NoOptDefVal
+}
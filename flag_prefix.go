@@ -0,0 +1,51 @@
+package flags
+
+import "strings"
+
+// 绑定结构体或者加入其他参数集合时的选项
+type Option func(o *bindOptions)
+
+type bindOptions struct {
+	prefix string
+}
+
+func newBindOptions(opts []Option) (o bindOptions) {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return
+}
+
+// 参数名加上前缀，如 WithPrefix("db-") 时 --host 变为 --db-host，环境变量 HOST 变为 DB_HOST
+//
+// 同一个结构体用在多个模块中时避免参数名冲突，加了前缀的参数不再使用短参数名
+func WithPrefix(prefix string) Option { return func(o *bindOptions) { o.prefix = prefix } }
+
+// 前缀对应的环境变量前缀，`-` 和 `.` 替换为 `_` 并转为大写，如 db- => DB_
+func envPrefix(prefix string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(prefix))
+}
+
+// 给参数名、环境变量加上前缀，分组标签和依赖的参数名也使用同样的前缀，避免和其他模块的合并
+func prefixField(field *FlagField, prefix string) {
+	if prefix == "" {
+		return
+	}
+
+	field.Name = prefix + field.Name
+	field.Shorthand = ""
+	field.prefix += prefix
+	field.Env = prefixEnv(field.Env, prefix)
+}
+
+func prefixEnv(keys []string, prefix string) (out []string) {
+	ep := envPrefix(prefix)
+	for _, key := range keys {
+		if k, ok := strings.CutPrefix(key, "*"); ok {
+			out = append(out, "*"+ep+k)
+		} else {
+			out = append(out, ep+key)
+		}
+	}
+	return
+}
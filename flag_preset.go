@@ -0,0 +1,82 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type preset struct {
+	name   string
+	values map[string]string
+}
+
+// 定义预设参数组，命令行中 `--<name>` 会展开为 values 中的参数值
+//
+// 命令行中显式设置的参数优先于预设，预设显示在帮助信息的 PRESETS 部分
+func DefinePreset(set *FlagSet, name string, values map[string]string) error {
+	if set.Lookup(name) != nil {
+		return fmt.Errorf("flag %q already defined", name)
+	}
+	if err := checkName(set, name); err != nil {
+		return err
+	}
+
+	p := &preset{name: name, values: values}
+	item := set.VarPF(&presetValue{set: set, preset: p}, name, "", "preset: "+p.String())
+	item.NoOptDefVal = "true"
+	item.Hidden = true
+
+	meta := metaOf(set)
+	meta.presets = append(meta.presets, p)
+	return nil
+}
+
+func (p *preset) keys() []string {
+	keys := make([]string, 0, len(p.values))
+	for k := range p.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (p *preset) String() string {
+	var items []string
+	for _, k := range p.keys() {
+		items = append(items, k+"="+p.values[k])
+	}
+	return strings.Join(items, ", ")
+}
+
+type presetValue struct {
+	set    *FlagSet
+	preset *preset
+}
+
+func (v *presetValue) String() string { return "false" }
+func (v *presetValue) Type() string   { return "bool" }
+func (v *presetValue) Set(s string) (err error) {
+	if s != "true" {
+		return fmt.Errorf("preset --%s does not take a value", v.preset.name)
+	}
+
+	values := map[string]string{}
+	for _, k := range v.preset.keys() {
+		if f := v.set.Lookup(k); f == nil || !f.Changed {
+			values[k] = v.preset.values[k]
+		}
+	}
+	return SetAll(v.set, values, SourceConfig)
+}
+
+func writePresets(out io.Writer, set *FlagSet) {
+	if presets := metaOf(set).presets; len(presets) > 0 {
+		fmt.Fprintf(out, "%s:\n", msg("PRESETS"))
+		for _, p := range presets {
+			fmt.Fprintf(out, "      --%s: %s\n", p.name, p)
+		}
+		fmt.Fprintln(out)
+	}
+}
@@ -0,0 +1,112 @@
+package flags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 通过 --print-config 输出了配置，设置了 ErrorHandling 时 ParseFlags 返回该错误而不是退出
+var ErrPrintConfig = errors.New("print config requested")
+
+// 脱敏后显示的值
+const redacted = "******"
+
+// 注册 --print-config[=json|yaml|toml] 参数，解析完成后(合并配置文件、环境变量和命令行之后)输出最终的配置并退出
+//
+// 标记了 `secret:"true"` 或者 MarkSecret 的字段输出为 ******，hidden 为 true 时不在帮助信息中显示
+func PrintConfigFlag(hidden bool, flags ...*FlagSet) {
+	set := flagSet(flags)
+	f := set.VarPF(&printConfigValue{}, "print-config", "", "输出最终的配置并退出: json, yaml, toml")
+	f.NoOptDefVal = "json"
+	f.Hidden = hidden
+}
+
+type printConfigValue struct{ format string }
+
+func (v *printConfigValue) String() string { return v.format }
+func (v *printConfigValue) Type() string   { return "format" }
+func (v *printConfigValue) Set(s string) error {
+	switch s {
+	case "json", "yaml", "toml":
+		v.format = s
+		return nil
+	default:
+		return fmt.Errorf("format must be one of [json, yaml, toml]; got %s", s)
+	}
+}
+
+// 解析完成后检查是否需要输出配置
+func printConfig(set *FlagSet) (printed bool, err error) {
+	f := set.Lookup("print-config")
+	if f == nil || !f.Changed {
+		return
+	}
+	v, ok := f.Value.(*printConfigValue)
+	if !ok {
+		return
+	}
+	return true, WriteConfig(os.Stdout, v.format, set)
+}
+
+// 按 json, yaml 或者 toml 格式输出所有绑定到结构体的参数的最终值，secret 字段脱敏
+func WriteConfig(w io.Writer, format string, flags ...*FlagSet) (err error) {
+	set := flagSet(flags)
+	values := map[string]any{}
+	for name, field := range metaOf(set).fields {
+		values[name] = configValue(field.Value)
+	}
+	doc := nest(values)
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err = enc.Encode(doc); err == nil {
+			err = enc.Close()
+		}
+		return
+	case "toml":
+		return toml.NewEncoder(w).Encode(doc)
+	default:
+		return fmt.Errorf("unsupported config format: %s", format)
+	}
+}
+
+// 基础类型保留原来的类型，其他类型(时长、地址等)使用显示的文本
+func configValue(v *value) any {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.sync()
+	if v.secret {
+		return redactValue(strings.Join(v.args, ","))
+	}
+
+	rv := reflect.Indirect(v.v)
+	if !rv.IsValid() {
+		return nil
+	}
+
+	if bt := baseType(v.typ); isBasic(bt) && !HasExtend(bt) && !isText(bt) && v.format == nil && rv.Kind() != reflect.Map {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil
+		}
+		return reflect.Indirect(rv).Interface()
+	}
+
+	if v.IsSlice() {
+		return append([]string{}, v.args...)
+	}
+	return strings.Join(v.args, ",")
+}
@@ -0,0 +1,50 @@
+package flags
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// 标签 `priority:"env>flag"`，返回优先级最高的来源
+func fieldPriority(tag reflect.StructTag) string {
+	first, _, _ := strings.Cut(getTag(tag, _TAG_PRIORITY), ">")
+	return strings.ToLower(strings.TrimSpace(first))
+}
+
+// 检查 priority 标签，只支持 env>flag 和默认的 flag>env
+func checkPriority(field *FlagField) error {
+	spec := getTag(field.Field.Tag, _TAG_PRIORITY)
+	if spec == "" {
+		return nil
+	}
+	switch strings.ToLower(strings.ReplaceAll(spec, " ", "")) {
+	case "env>flag", "flag>env":
+		return nil
+	}
+	return fmt.Errorf("flag %q: unsupported priority %q, want env>flag or flag>env", field.Name, spec)
+}
+
+// 解析完成后，用环境变量覆盖标记了 `priority:"env>flag"` 的参数
+func applyPriority(set *FlagSet) {
+	for _, field := range metaOf(set).fields {
+		if !field.Value.envFirst {
+			continue
+		}
+
+		for _, key := range envKeys(field) {
+			ev := getenv(key)
+			if ev == "" {
+				continue
+			}
+
+			changed := field.Value.changed
+			field.Value.changed = false
+			if field.Value.SetDefault(ev) == nil {
+				field.Value.setSource(envSource(key), key)
+				break
+			}
+			field.Value.changed = changed
+		}
+	}
+}
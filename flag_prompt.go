@@ -0,0 +1,125 @@
+package flags
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var (
+	promptIn  io.Reader = os.Stdin
+	promptOut io.Writer = os.Stderr
+
+	// 标准输入是否为终端，不是终端时(管道、后台服务)不提示
+	stdinIsTerminal = func() bool {
+		fi, err := os.Stdin.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0
+	}
+
+	// 通过 stty 关闭回显，没有 stty 的系统(如 Windows)输入会显示出来
+	setEcho = func(on bool) {
+		arg := "-echo"
+		if on {
+			arg = "echo"
+		}
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		_ = cmd.Run()
+	}
+)
+
+// 参数没有设置时(命令行、环境变量、配置文件都没有值)，从终端提示输入，敏感参数输入时不回显
+//
+// 绑定的结构体字段也可以使用标签 `prompt:"Enter password:"`
+func MarkPrompt(set *FlagSet, name, message string) error {
+	if set.Lookup(name) == nil {
+		return fmt.Errorf("flag %q not defined", name)
+	}
+	meta := metaOf(set)
+	if meta.prompts == nil {
+		meta.prompts = map[string]string{}
+	}
+	meta.prompts[name] = message
+	return nil
+}
+
+// 关闭交互输入，用于 CI 等无人值守的场景，环境变量 CI 不为空时也不会提示
+func NoPrompt(flags ...*FlagSet) { metaOf(flagSet(flags)).noPrompt = true }
+
+func fieldPrompt(set *FlagSet, field *FlagField) {
+	if message := getTag(field.Field.Tag, _TAG_PROMPT); message != "" {
+		_ = MarkPrompt(set, field.Name, message)
+	}
+}
+
+// 依次提示输入没有值的参数，ctx 取消时停止等待
+func promptMissing(set *FlagSet) (err error) {
+	meta := metaOf(set)
+	if len(meta.prompts) == 0 || meta.noPrompt || getenv("CI") != "" || !stdinIsTerminal() {
+		return
+	}
+	defer meta.stats.load(SourcePrompt, time.Now())
+
+	var reader *bufio.Reader
+	set.VisitAll(func(f *Flag) {
+		message, found := meta.prompts[f.Name]
+		if err != nil || !found || isProvided(f) {
+			return
+		}
+
+		if reader == nil {
+			reader = bufio.NewReader(promptIn)
+		}
+
+		var line string
+		if line, err = promptLine(set, reader, message, isSecret(set, f)); err != nil || line == "" {
+			return
+		}
+
+		if err = f.Value.Set(line); err != nil {
+			err = fmt.Errorf("invalid argument %q for %q flag: %v", line, "--"+f.Name, err)
+			return
+		}
+		f.Changed = true
+		if v, ok := f.Value.(*value); ok {
+			v.setSource(SourcePrompt, "")
+		}
+	})
+	return
+}
+
+func promptLine(set *FlagSet, reader *bufio.Reader, message string, secret bool) (string, error) {
+	fmt.Fprintf(promptOut, "%s ", strings.TrimSpace(message))
+	if secret {
+		setEcho(false)
+		defer func() {
+			setEcho(true)
+			fmt.Fprintln(promptOut)
+		}()
+	}
+
+	type result struct {
+		line string
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		ch <- result{strings.TrimRight(line, "\r\n"), err}
+	}()
+
+	ctx := contextOf(set)
+	select {
+	case r := <-ch:
+		return r.line, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
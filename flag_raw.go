@@ -0,0 +1,134 @@
+package flags
+
+import "strings"
+
+// 命令行中某次出现的参数
+type rawToken struct {
+	Flag *Flag    // 未定义的参数为 nil
+	Name string   // 命令行中写的长名称或者短名称
+	Raw  []string // 原始写法，如 ["--port=80"]、["-p", "80"]
+}
+
+// 按照 pflag 的规则扫描命令行参数，遇到 `--` 停止，关闭 Interspersed 时遇到第一个位置参数停止
+func scanArgs(set *FlagSet, args []string) (tokens []rawToken) {
+	stopAtPositional := !interspersed(set)
+	needValue := func(f *Flag, i int) bool {
+		if i+1 >= len(args) {
+			return false
+		}
+		if f == nil {
+			return !strings.HasPrefix(args[i+1], "-")
+		}
+		return f.NoOptDefVal == ""
+	}
+
+	for i := 0; i < len(args); i++ {
+		s := args[i]
+		if s == "--" {
+			break
+		}
+
+		if len(s) < 2 || s[0] != '-' {
+			if stopAtPositional {
+				break
+			}
+			continue
+		}
+
+		if s[1] == '-' {
+			name, _, hasValue := strings.Cut(s[2:], "=")
+			if name == "" {
+				continue
+			}
+			f := set.Lookup(name)
+			if f == nil {
+				// 缩写和写法不同的参数名，保留命令行中的原始写法
+				if full, _ := matchName(set, name); full != "" {
+					f = set.Lookup(full)
+				}
+			}
+			t := rawToken{Flag: f, Name: name, Raw: args[i : i+1]}
+			if !hasValue && needValue(f, i) {
+				t.Raw = args[i : i+2]
+				i++
+			}
+			tokens = append(tokens, t)
+			continue
+		}
+
+		start := i
+		for shorts := s[1:]; shorts != ""; shorts = shorts[1:] {
+			c := shorts[:1]
+			f := set.ShorthandLookup(c)
+			t := rawToken{Flag: f, Name: c, Raw: args[start : i+1]}
+			switch {
+			case len(shorts) > 2 && shorts[1] == '=':
+				shorts = shorts[:1]
+			case f == nil:
+				if len(shorts) == 1 && needValue(nil, i) {
+					i++
+				}
+			case f.NoOptDefVal != "":
+			case len(shorts) > 1:
+				shorts = shorts[:1]
+			case needValue(f, i):
+				i++
+			}
+			t.Raw = args[start : i+1]
+			tokens = append(tokens, t)
+		}
+	}
+	return
+}
+
+// 返回参数在最近一次解析的命令行中的原始写法，多次出现时按顺序拼接
+func RawArgs(name string, flags ...*FlagSet) []string {
+	set := flagSet(flags)
+	if f := set.Lookup(name); f != nil {
+		return metaOf(set).raw[f.Name]
+	}
+	return nil
+}
+
+func recordRaw(set *FlagSet, args []string) {
+	raw := map[string][]string{}
+	var unknown []string
+	for _, t := range scanArgs(set, args) {
+		if t.Flag != nil {
+			raw[t.Flag.Name] = append(raw[t.Flag.Name], t.Raw...)
+		} else {
+			unknown = append(unknown, t.unknownArgs()...)
+		}
+	}
+	meta := metaOf(set)
+	meta.raw, meta.unknown = raw, unknown
+}
+
+// 未定义参数的原始写法，合并的短参数如 -xv 中只取出自身
+func (t rawToken) unknownArgs() []string {
+	if len(t.Name) > 1 || strings.HasPrefix(t.Raw[0], "--") {
+		return t.Raw
+	}
+
+	short := "-" + t.Name
+	switch {
+	case len(t.Raw) == 2:
+		return []string{short, t.Raw[1]}
+	case strings.HasPrefix(t.Raw[0], short+"="):
+		return t.Raw
+	default:
+		return []string{short}
+	}
+}
+
+// 忽略未定义的参数，不会导致解析失败，忽略的参数可以通过 UnknownArgs 取得
+//
+// 未定义的参数后面不以 `-` 开头的参数会作为它的值一起忽略
+func IgnoreUnknown(flags ...*FlagSet) {
+	flagSet(flags).ParseErrorsWhitelist.UnknownFlags = true
+}
+
+// 最近一次 ParseFlags 中未定义的参数，按原始写法和顺序返回，用于转交给其他程序或者再次解析
+func UnknownArgs(flags ...*FlagSet) []string {
+	return metaOf(flagSet(flags)).unknown
+}
@@ -0,0 +1,270 @@
+package flags
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 订阅断开后重新连接的间隔
+var RedisRetryInterval = 5 * time.Second
+
+// 回复中字符串和数组长度的上限，超过时返回错误，避免按服务端给出的长度分配过多内存
+const (
+	redisMaxBulk  = 16 << 20
+	redisMaxArray = 1 << 20
+)
+
+// 从 Redis 读取配置，未定义的参数和来自命令行、环境变量的参数会忽略
+//
+// addr 为 redis://[[user]:password@]host[:port][/db]。key 为 hash 时每个字段为参数名和值，
+// 为字符串时为 JSON 文档，嵌套的对象以 `.` 连接作为参数名
+func LoadRedis(ctx context.Context, addr, key string, flags ...*FlagSet) error {
+	set := flagSet(flags)
+	values, err := fetchRedis(ctx, addr, key)
+	if err != nil {
+		return err
+	}
+	return SetAll(set, configValues(set, values), SourceConfig)
+}
+
+// 读取一次配置后订阅 channel，收到消息时重新读取并写入变化的值，ctx 取消后停止
+//
+// 消息的内容不使用，发布任意内容都会触发重新读取。订阅断开后每隔 RedisRetryInterval 重新连接，连接后重新读取一次。
+// 变化的值通过 SetAll 全部校验通过后才写入，写入后调用 OnReload 注册的函数。
+// 第一次读取失败时返回错误，之后读取、写入或者 OnReload 出错时调用 onError，onError 可以为 nil。
+// 也可以通过 Refresh 立即重新读取
+func WatchRedis(ctx context.Context, addr, key, channel string, onError func(error), flags ...*FlagSet) error {
+	set := flagSet(flags)
+	r := &remoteReload{set: set}
+	refresh := addWatcher(ctx, set, func(ctx context.Context) error {
+		values, err := fetchRedis(ctx, addr, key)
+		if err != nil {
+			return err
+		}
+		return r.apply(ctx, values)
+	})
+	if err := refresh(ctx); err != nil {
+		return err
+	}
+
+	report := func(err error) {
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+
+	go func() {
+		for {
+			report(subscribeRedis(ctx, addr, channel, func() { report(refresh(ctx)) }))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(RedisRetryInterval):
+			}
+		}
+	}()
+	return nil
+}
+
+func fetchRedis(ctx context.Context, addr, key string) (values map[string]string, err error) {
+	conn, err := dialRedis(ctx, addr)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	typ, err := conn.do("TYPE", key)
+	if err != nil {
+		return
+	}
+
+	values = map[string]string{}
+	switch typ {
+	case "hash":
+		reply, err := conn.do("HGETALL", key)
+		if err != nil {
+			return nil, err
+		}
+		items, _ := reply.([]any)
+		for i := 0; i+1 < len(items); i += 2 {
+			name, _ := items[i].(string)
+			values[name], _ = items[i+1].(string)
+		}
+	case "string":
+		reply, err := conn.do("GET", key)
+		if err != nil {
+			return nil, err
+		}
+		doc, _ := reply.(string)
+		var v any
+		if err = json.Unmarshal([]byte(doc), &v); err != nil {
+			return nil, fmt.Errorf("invalid json config in redis key %q: %w", key, err)
+		}
+		flattenJSON("", v, values)
+	case "none":
+		return nil, fmt.Errorf("redis key %q not found", key)
+	default:
+		return nil, fmt.Errorf("redis key %q must be a hash or a json string; got %v", key, typ)
+	}
+	return
+}
+
+// 订阅 channel，订阅成功后和每次收到消息时调用 refresh，连接断开时返回错误，ctx 取消时返回 nil
+func subscribeRedis(ctx context.Context, addr, channel string, refresh func()) error {
+	conn, err := dialRedis(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.do("SUBSCRIBE", channel); err != nil {
+		return err
+	}
+	// 断开期间的变化没有收到通知
+	refresh()
+
+	for {
+		reply, err := conn.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("redis subscribe %s: %w", channel, err)
+		}
+		if msg, _ := reply.([]any); len(msg) == 3 && msg[0] == "message" {
+			refresh()
+		}
+	}
+}
+
+// 最简单的 RESP 协议客户端，只用于读取配置和订阅，不引入第三方依赖
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	stop chan struct{}
+}
+
+func dialRedis(ctx context.Context, addr string) (*redisConn, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" || u.Hostname() == "" {
+		return nil, fmt.Errorf("redis url must be redis://[[user]:password@]host[:port][/db]; got %s", addr)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	// ctx 取消时关闭连接，让阻塞的读取返回
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), stop: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-c.stop:
+		}
+	}()
+
+	if password, ok := u.User.Password(); ok {
+		args := []string{"AUTH", password}
+		if user := u.User.Username(); user != "" {
+			args = []string{"AUTH", user, password}
+		}
+		if _, err = c.do(args...); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" && db != "0" {
+		if _, err = c.do("SELECT", db); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) Close() error {
+	close(c.stop)
+	return c.conn.Close()
+}
+
+func (c *redisConn) do(args ...string) (any, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		if n > redisMaxBulk {
+			return nil, fmt.Errorf("redis: bulk reply of %d bytes exceeds %d", n, redisMaxBulk)
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		if n > redisMaxArray {
+			return nil, fmt.Errorf("redis: array reply of %d items exceeds %d", n, redisMaxArray)
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
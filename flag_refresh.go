@@ -0,0 +1,107 @@
+package flags
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 已安装的文档、补全脚本由旧版本的程序生成时重新生成，如
+//
+//	flags.RefreshDoc("/usr/share/doc/app/flags.md", func(w io.Writer) error { return flags.GenMarkdown(w) }, flags.ConfirmTerminal)
+//
+// 生成的文件第一行记录程序名和版本号(按扩展名使用对应的注释格式)，只处理已经存在并且带有该记录的文件。
+// 版本号不同时调用 confirm 征得用户同意后再写入，confirm 为 nil 时不更新
+func RefreshDoc(path string, gen func(w io.Writer) error, confirm func(path, installed, current string) bool, flags ...*FlagSet) (updated bool, err error) {
+	set := flagSet(flags)
+	current := versionOf(set)
+	if current == "" {
+		return
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return
+	}
+	installed, found := generatedVersion(f, nameOf(set))
+	f.Close()
+
+	if !found || installed == current || confirm == nil || !confirm(path, installed, current) {
+		return
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, generatedMarker(path, nameOf(set), current))
+	if err = gen(&buf); err != nil {
+		return
+	}
+
+	// 先写入临时文件再替换，避免中途出错时留下不完整的文件
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	return true, nil
+}
+
+// 生成带版本记录的文件，用于安装时第一次生成，之后通过 RefreshDoc 更新
+func WriteDoc(path string, gen func(w io.Writer) error, flags ...*FlagSet) error {
+	set := flagSet(flags)
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, generatedMarker(path, nameOf(set), versionOf(set)))
+	if err := gen(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// 在终端中询问是否更新，不是终端或者设置了环境变量 CI 时不更新
+func ConfirmTerminal(path, installed, current string) bool {
+	if getenv("CI") != "" || !stdinIsTerminal() {
+		return false
+	}
+	fmt.Fprintf(promptOut, "%s 由 %s 版本生成，是否更新为 %s 版本? [y/N] ", path, installed, current)
+	line, _ := bufio.NewReader(promptIn).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// 记录版本号的注释，如 `# generated by app 1.0.0`
+func generatedMarker(path, name, version string) string {
+	text := "generated by " + name + " " + version
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case ext == ".md" || ext == ".html" || ext == ".xml":
+		return "<!-- " + text + " -->"
+	case len(ext) == 2 && ext[1] >= '1' && ext[1] <= '9':
+		return `.\" ` + text
+	default: // shell 补全脚本等
+		return "# " + text
+	}
+}
+
+// 从文件开头几行中找到版本记录
+func generatedVersion(r io.Reader, name string) (version string, found bool) {
+	prefix := "generated by " + name + " "
+	scanner := bufio.NewScanner(r)
+	for i := 0; i < 5 && scanner.Scan(); i++ {
+		if _, after, ok := strings.Cut(scanner.Text(), prefix); ok {
+			version = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(after), "-->"))
+			return version, true
+		}
+	}
+	return
+}
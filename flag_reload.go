@@ -0,0 +1,213 @@
+package flags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 热加载执行命令时，通过该环境变量传递变化的参数名，多个以 `,` 连接
+const ReloadChangedEnvKey = "FLAGS_CHANGED"
+
+// 热加载(WatchConfig、WatchSQL)写入变化的值后调用 fn，changed 为变化的参数名 => 新的值
+//
+// fn 返回的错误交给 Watch 的 onError 处理
+func OnReload(fn func(ctx context.Context, changed map[string]string) error, flags ...*FlagSet) {
+	meta := metaOf(flagSet(flags))
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	meta.onReload = append(meta.onReload, fn)
+}
+
+// 注册 --on-reload 参数，热加载写入变化后执行该命令，如 --on-reload "nginx -s reload"
+//
+// 命令按空白分割，不经过 shell。变化的参数名通过环境变量 FLAGS_CHANGED 传递，
+// 同时以 JSON 对象(参数名 => 新的值，敏感参数显示为 ******)写入命令的标准输入
+func ReloadCommandFlag(flags ...*FlagSet) {
+	set := flagSet(flags)
+	set.String("on-reload", "", "配置热加载后执行的命令")
+	OnReload(func(ctx context.Context, changed map[string]string) error {
+		args := strings.Fields(set.Lookup("on-reload").Value.String())
+		if len(args) == 0 {
+			return nil
+		}
+		return runReloadCommand(ctx, set, args, changed)
+	}, set)
+}
+
+func runReloadCommand(ctx context.Context, set *FlagSet, args []string, changed map[string]string) error {
+	names := make([]string, 0, len(changed))
+	shown := make(map[string]string, len(changed))
+	for name, s := range changed {
+		names = append(names, name)
+		if f := set.Lookup(name); f != nil && isSecret(set, f) {
+			s = redactValue(s)
+		}
+		shown[name] = s
+	}
+	sort.Strings(names)
+
+	input, err := json.Marshal(shown)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), ReloadChangedEnvKey+"="+strings.Join(names, ","))
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("reload command %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// 热加载写入变化的值后依次调用 OnReload 注册的函数
+func reloaded(ctx context.Context, set *FlagSet, changed map[string]string) error {
+	if len(changed) == 0 {
+		return nil
+	}
+
+	meta := metaOf(set)
+	meta.mu.Lock()
+	hooks := meta.onReload
+	meta.mu.Unlock()
+
+	var errs []error
+	for _, fn := range hooks {
+		if err := fn(ctx, changed); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 轮询数据源的设置，每个 Watch*Poll 调用单独设置
+type Poll struct {
+	Interval time.Duration // 两次读取的间隔
+	Jitter   time.Duration // 每次间隔随机增加 [0, Jitter)，避免多个实例的请求总是同时到达
+	MaxSkew  time.Duration // 第一次轮询前随机多等待 [0, MaxSkew)，错开同时启动的实例
+}
+
+func (p Poll) check() error {
+	if p.Interval <= 0 || p.Jitter < 0 || p.MaxSkew < 0 {
+		return fmt.Errorf("invalid poll: interval must be positive, jitter and max skew must not be negative")
+	}
+	return nil
+}
+
+func randDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// 按设置的间隔调用 fn，ctx 取消后返回
+func (p Poll) run(ctx context.Context, fn func()) {
+	wait := p.Interval + randDuration(p.MaxSkew)
+	for {
+		timer := time.NewTimer(wait + randDuration(p.Jitter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		fn()
+		wait = p.Interval
+	}
+}
+
+type watcher struct {
+	ctx context.Context // Watch* 的 ctx，取消后 Refresh 不再读取
+	run func(ctx context.Context) error
+}
+
+// 注册热加载的一次读取，轮询和 Refresh 共用，同一个数据源的读取串行执行
+func addWatcher(ctx context.Context, set *FlagSet, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	var mu sync.Mutex
+	run := func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return fn(ctx)
+	}
+
+	meta := metaOf(set)
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	meta.watchers = append(meta.watchers, watcher{ctx: ctx, run: run})
+	return run
+}
+
+// 立即重新读取 WatchConfig、WatchSQL、WatchRedis 等监视的数据源并写入变化的值，不等待下一次轮询
+//
+// 依次读取所有数据源，返回所有数据源的错误
+func Refresh(ctx context.Context, flags ...*FlagSet) error {
+	meta := metaOf(flagSet(flags))
+	meta.mu.Lock()
+	var live []watcher
+	for _, w := range meta.watchers {
+		if w.ctx.Err() == nil {
+			live = append(live, w)
+		}
+	}
+	meta.watchers = live
+	meta.mu.Unlock()
+
+	var errs []error
+	for _, w := range live {
+		if err := w.run(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 远程配置(数据库、Redis)的热加载状态
+type remoteReload struct {
+	set    *FlagSet
+	last   map[string]string
+	loaded bool
+}
+
+// 写入读取到的配置，和上一次相同时跳过。未定义的参数和来源优先级高于配置文件的参数(命令行、环境变量等)忽略
+//
+// SetAll 全部校验通过后才写入，出错时保持原来的值。第一次之后的变化写入后调用 OnReload 注册的函数
+func (r *remoteReload) apply(ctx context.Context, values map[string]string) error {
+	values = configValues(r.set, values)
+	changed := changedValues(r.last, values)
+	if r.loaded && len(changed) == 0 && len(values) == len(r.last) {
+		return nil
+	}
+	if err := SetAll(r.set, values, SourceConfig); err != nil {
+		return err
+	}
+
+	loaded := r.loaded
+	r.last, r.loaded = values, true
+	if !loaded {
+		return nil
+	}
+	return reloaded(ctx, r.set, changed)
+}
+
+// 变化了的值，包括新增的和值不同的
+func changedValues(last, cur map[string]string) map[string]string {
+	changed := map[string]string{}
+	for name, s := range cur {
+		if prev, ok := last[name]; !ok || prev != s {
+			changed[name] = s
+		}
+	}
+	return changed
+}
@@ -0,0 +1,49 @@
+package flags
+
+import "fmt"
+
+// 标记参数为敏感信息，帮助信息、--print-config、PrintSources 和 StructPrint 中显示为 ******
+//
+// 绑定的结构体字段也可以使用标签 `secret:"true"`，StructPrint 只识别标签
+func MarkSecret(set *FlagSet, names ...string) error {
+	meta := metaOf(set)
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	for _, name := range names {
+		f := set.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("flag %q not defined", name)
+		}
+		if v, ok := f.Value.(*value); ok {
+			v.mu.Lock()
+			v.secret = true
+			v.mu.Unlock()
+			continue
+		}
+		if meta.secrets == nil {
+			meta.secrets = map[string]bool{}
+		}
+		meta.secrets[name] = true
+	}
+	return nil
+}
+
+func isSecret(set *FlagSet, f *Flag) bool {
+	if v, ok := f.Value.(*value); ok {
+		v.mu.Lock()
+		secret := v.secret
+		v.mu.Unlock()
+		if secret {
+			return true
+		}
+	}
+	return metaOf(set).markedSecret(f.Name)
+}
+
+// 有值时显示为 ******，空值保持不变，便于看出是否已设置
+func redactValue(s string) string {
+	if s == "" || s == "[]" {
+		return s
+	}
+	return redacted
+}
@@ -0,0 +1,141 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 解析密钥引用，ref 为去掉 `<scheme>:` 前缀之后的部分，如 `vault:secret/data/app#password` 中的 `secret/data/app#password`
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// 同 SecretResolver，普通函数
+type SecretResolverFunc func(ctx context.Context, ref string) (string, error)
+
+func (f SecretResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+var secretResolvers map[string]SecretResolver
+
+// 解析一个密钥引用的超时时间
+var SecretTimeout = 10 * time.Second
+
+// 注册密钥引用的解析，参数值(命令行、环境变量、配置文件)以 `<scheme>:` 开头时通过 r 解析为实际的值
+//
+//	flags.RegisterSecretResolver("vault", flags.NewVaultResolver())
+//	--db.password vault:secret/data/app#password
+//
+// 解析后的参数自动标记为敏感信息，输出时脱敏，Args 中保留原来的引用
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	if secretResolvers == nil {
+		secretResolvers = map[string]SecretResolver{}
+	}
+	secretResolvers[scheme] = r
+}
+
+// 解析密钥引用，不是已注册的 scheme 时原样返回，ctx 为解析的 context，取消时停止请求
+func resolveSecret(ctx context.Context, s string) (out string, resolved bool, err error) {
+	scheme, ref, ok := strings.Cut(s, ":")
+	if !ok || secretResolvers == nil {
+		return s, false, nil
+	}
+	r, found := secretResolvers[scheme]
+	if !found {
+		return s, false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SecretTimeout)
+	defer cancel()
+	if out, err = r.Resolve(ctx, ref); err != nil {
+		return "", false, fmt.Errorf("resolve secret %s: %w", s, err)
+	}
+	return out, true, nil
+}
+
+// 读取 HashiCorp Vault 的 KV 密钥，兼容 KV v1 和 v2
+//
+// ref 为 `<path>#<key>`，如 `secret/data/app#password`，只有一个键时可以省略 key
+type VaultResolver struct {
+	Addr      string // 默认读取环境变量 VAULT_ADDR
+	Token     string // 默认读取环境变量 VAULT_TOKEN
+	Namespace string // 默认读取环境变量 VAULT_NAMESPACE
+	Client    *http.Client
+}
+
+// 使用 VAULT_ADDR, VAULT_TOKEN, VAULT_NAMESPACE 环境变量创建 VaultResolver
+func NewVaultResolver() *VaultResolver {
+	return &VaultResolver{Addr: getenv("VAULT_ADDR"), Token: getenv("VAULT_TOKEN"), Namespace: getenv("VAULT_NAMESPACE")}
+}
+
+func (r *VaultResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	if r.Addr == "" {
+		return "", fmt.Errorf("vault address is required")
+	}
+
+	path, key, _ := strings.Cut(ref, "#")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(r.Addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	if r.Token != "" {
+		req.Header.Set("X-Vault-Token", r.Token)
+	}
+	if r.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.Namespace)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault %s: %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", err
+	}
+
+	// KV v2 的值在 data.data 中
+	data := body.Data
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = inner
+		}
+	}
+
+	if key == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("vault %s: key is required, has %d keys", path, len(data))
+		}
+		for k := range data {
+			key = k
+		}
+	}
+
+	v, found := data[key]
+	if !found {
+		return "", fmt.Errorf("vault %s: key %s not found", path, key)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,86 @@
+package flags
+
+import (
+	"context"
+
+	"github.com/spf13/pflag"
+)
+
+// 独立的参数集合，不修改 pflag.CommandLine 和全局的程序名、版本号，适合库内部使用
+//
+//	set := flags.New("app")
+//	set.Struct(&cfg)
+//	err := set.ParseArgs(os.Args[1:])
+//
+// 除了 Struct, ParseArgs 等方法，也可以传给所有接受 ...*FlagSet 参数的函数。
+//
+// 参数、版本号和绑定信息属于各自的参数集合，但绑定信息保存在包内按参数集合索引的表中，
+// 不再使用时需要调用 Release 释放。以下设置仍然是进程内共享的，所有参数集合都会受影响:
+// Init 设置的环境变量和程序名、RegisterSecretResolver、RegisterDefault、SetMessages 和 TypeName，
+// 以及云厂商元数据的缓存
+type Set struct {
+	*FlagSet
+}
+
+// 创建独立的参数集合，name 为空时使用全局的程序名，解析出错时总是返回错误而不是退出
+func New(name string) *Set {
+	if name == "" {
+		name = nameOf(nil)
+	}
+	set := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	set.SortFlags = false
+	metaOf(set).name = name
+	ErrorHandling(pflag.ContinueOnError, set)
+	return &Set{FlagSet: set}
+}
+
+// 设置版本号，不为空时注册 --version 参数
+func (s *Set) SetVersion(version string) *Set {
+	metaOf(s.FlagSet).version = version
+	return s
+}
+
+// 绑定结构体，同 StructBindE，可以通过 WithPrefix 给参数名加上前缀
+func (s *Set) Struct(structPtr any, opts ...Option) error {
+	return structBind(structPtr, s.FlagSet, newBindOptions(opts))
+}
+
+// 解析参数，同 ParseFlags
+func (s *Set) ParseArgs(args []string) error { return ParseFlags(s.FlagSet, args) }
+
+// 解析参数，同 ParseFlagsContext
+func (s *Set) ParseContext(ctx context.Context, args []string) error {
+	return ParseFlagsContext(ctx, s.FlagSet, args)
+}
+
+// 立即重新读取监视的数据源，同 Refresh
+func (s *Set) Refresh(ctx context.Context) error { return Refresh(ctx, s.FlagSet) }
+
+// 所有参数的当前值，同 Values
+func (s *Set) Values() map[string]string { return Values(s.FlagSet) }
+
+// 帮助信息，同 RenderHelp
+func (s *Set) Help() string { return RenderHelp(s.FlagSet) }
+
+// 释放附加在参数集合上的扩展信息，不再使用时调用，同 Release
+func (s *Set) Release() { Release(s.FlagSet) }
+
+// 参数集合的程序名，New 创建的使用自己的名字
+func nameOf(set *FlagSet) string {
+	if set != nil {
+		if m := lookupMeta(set); m != nil && m.name != "" {
+			return m.name
+		}
+	}
+	return name()
+}
+
+// 参数集合的版本号，New 创建的不使用全局的版本号
+func versionOf(set *FlagSet) string {
+	if set != nil {
+		if m := lookupMeta(set); m != nil && m.name != "" {
+			return m.version
+		}
+	}
+	return version
+}
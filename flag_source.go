@@ -0,0 +1,167 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// 参数值的来源
+type Source int
+
+const (
+	SourceDefault Source = iota
+	SourceConfig
+	SourceEnv
+	SourceFlag
+	SourceMetadata // 运行环境的元数据，如 Kubernetes Downward API
+	SourcePrompt   // 终端交互输入
+	SourceParent   // 父进程通过 InheritEnv 传递的环境变量
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceConfig:
+		return "config"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	case SourceMetadata:
+		return "metadata"
+	case SourcePrompt:
+		return "prompt"
+	case SourceParent:
+		return "parent"
+	default:
+		return fmt.Sprintf("source(%d)", int(s))
+	}
+}
+
+// 批量设置参数值，全部校验通过后才会写入，有任何一个错误都不会修改已有的值
+//
+// source 为 SourceFlag 时等同于命令行设置，其他来源作为默认值写入。切片类型的值以 `,` 分隔
+func SetAll(set *FlagSet, values map[string]string, source Source) (err error) {
+	meta := metaOf(set)
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := set.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("flag %q not defined", name)
+		}
+		if v, ok := f.Value.(*value); ok {
+			if err = v.check(v.split(values[name])...); err != nil {
+				return fmt.Errorf("invalid value %q for flag --%s: %w", values[name], f.Name, err)
+			}
+		}
+	}
+
+	for _, name := range names {
+		f := set.Lookup(name)
+		v, ok := f.Value.(*value)
+		switch {
+		case source == SourceFlag && ok:
+			for _, s := range v.split(values[name]) {
+				if err = set.Set(f.Name, s); err != nil {
+					return
+				}
+			}
+		case source == SourceFlag:
+			err = set.Set(f.Name, values[name])
+		case ok && v.omitempty && v.isZero(v.split(values[name])...):
+		case ok:
+			err = v.setDefault(source, "", v.split(values[name])...)
+		default:
+			err = f.Value.Set(values[name])
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 在临时变量上校验参数值
+func (v *value) check(args ...string) (err error) {
+	tmp := reflect.New(v.typ).Elem()
+	for i, arg := range args {
+		if err = validateRules(v.typ, arg, v.rules, v.parse); err != nil {
+			return
+		}
+		if err = v.rset(tmp, arg, i == 0); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (v *value) isZero(args ...string) bool {
+	tmp := reflect.New(v.typ).Elem()
+	for i, arg := range args {
+		if v.rset(tmp, arg, i == 0) != nil {
+			return false
+		}
+	}
+	return tmp.IsZero()
+}
+
+func (v *value) split(s string) []string {
+	if v.IsSlice() {
+		return strings.Split(s, ",")
+	}
+	return []string{s}
+}
+
+// 参数当前值的来源，key 为来源的位置: 环境变量名、配置文件路径或者 Downward API 文件名
+func FlagSource(name string, flags ...*FlagSet) (src Source, key string) {
+	f := flagSet(flags).Lookup(name)
+	if f == nil {
+		return
+	}
+	if v, ok := f.Value.(*value); ok {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		return v.source, v.sourceKey
+	}
+	if f.Changed {
+		return SourceFlag, ""
+	}
+	return
+}
+
+// 输出每个参数的最终值和来源，用于排查优先级的问题
+//
+//	--port = 8080 (env: APP_PORT)
+//	--host = db (config: /etc/app.yaml)
+func PrintSources(w io.Writer, flags ...*FlagSet) (err error) {
+	set := flagSet(flags)
+
+	var sb strings.Builder
+	set.VisitAll(func(f *Flag) {
+		src, key := FlagSource(f.Name, set)
+		val := currentValue(f)
+		if isSecret(set, f) {
+			val = redactValue(val)
+		}
+		if key != "" {
+			fmt.Fprintf(&sb, "--%s = %s (%s: %s)\n", f.Name, val, src, key)
+		} else {
+			fmt.Fprintf(&sb, "--%s = %s (%s)\n", f.Name, val, src)
+		}
+	})
+
+	_, err = io.WriteString(w, sb.String())
+	return
+}
@@ -0,0 +1,181 @@
+package flags
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 参数定义文件，可以是 json, yaml, toml 格式
+//
+//	flags:
+//	  - name: port
+//	    shorthand: p
+//	    type: int
+//	    default: 8080
+//	    usage: 监听端口
+//	    env: [PORT]
+type Spec struct {
+	Flags []FlagSpec `json:"flags" yaml:"flags" toml:"flags"`
+}
+
+type FlagSpec struct {
+	Name      string   `json:"name" yaml:"name" toml:"name"`
+	Shorthand string   `json:"shorthand,omitempty" yaml:"shorthand,omitempty" toml:"shorthand,omitempty"`
+	Type      string   `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"`
+	Default   any      `json:"default,omitempty" yaml:"default,omitempty" toml:"default,omitempty"`
+	Usage     string   `json:"usage,omitempty" yaml:"usage,omitempty" toml:"usage,omitempty"`
+	Env       []string `json:"env,omitempty" yaml:"env,omitempty" toml:"env,omitempty"`
+	Required  bool     `json:"required,omitempty" yaml:"required,omitempty" toml:"required,omitempty"`
+	Validate  string   `json:"validate,omitempty" yaml:"validate,omitempty" toml:"validate,omitempty"`
+}
+
+// 解析参数定义文件
+func ParseSpec(contentType string, data []byte) (spec *Spec, err error) {
+	spec = &Spec{}
+	if err = LoadConfig(spec, contentType, data); err != nil {
+		spec = nil
+	}
+	return
+}
+
+// 按照参数定义注册参数，参数值可以通过 Values 读取
+func SpecBind(spec *Spec, flags ...*FlagSet) (err error) {
+	set := flagSet(flags)
+	meta := metaOf(set)
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+
+	for _, fs := range spec.Flags {
+		t, e := specType(fs.Type)
+		if e != nil {
+			return fmt.Errorf("flag %s: %w", fs.Name, e)
+		}
+
+		v := reflect.New(t).Elem()
+		field := &FlagField{
+			Field:     reflect.StructField{Name: fs.Name, Type: t},
+			Name:      fs.Name,
+			Shorthand: fs.Shorthand,
+			Usage:     fs.Usage,
+			Env:       fs.Env,
+			Value:     newValue(v, t),
+			Referer:   v,
+		}
+		field.Value.rules = parseRules(fs.Validate)
+
+		if args := specDefault(fs.Default); len(args) > 0 {
+			if err = field.Value.SetDefault(args...); err != nil {
+				return fmt.Errorf("flag %s: invalid default value: %w", fs.Name, err)
+			}
+		}
+
+		if err = bindField(set, field, "spec"); err != nil {
+			return
+		}
+
+		if fs.Required {
+			if err = MarkOneRequired(set, fs.Name); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// 根据参数定义生成 Go 结构体代码
+func GenSpecStruct(w io.Writer, spec *Spec, typeName string) (err error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "type %s struct {\n", typeName)
+	for _, fs := range spec.Flags {
+		t, e := specType(fs.Type)
+		if e != nil {
+			return fmt.Errorf("flag %s: %w", fs.Name, e)
+		}
+
+		tags := []string{fs.Name}
+		if fs.Shorthand != "" {
+			tags = append(tags, fs.Shorthand)
+		}
+		tag := fmt.Sprintf("flag:%q", strings.Join(tags, ","))
+		if len(fs.Env) > 0 {
+			tag += fmt.Sprintf(" env:%q", strings.Join(fs.Env, ","))
+		}
+		if fs.Usage != "" {
+			tag += fmt.Sprintf(" usage:%q", fs.Usage)
+		}
+		if fs.Validate != "" {
+			tag += fmt.Sprintf(" validate:%q", fs.Validate)
+		}
+		if fs.Required {
+			tag += fmt.Sprintf(" onerequired:%q", fs.Name)
+		}
+
+		fmt.Fprintf(&buf, "\t%s %s `%s`", goName(fs.Name), t, tag)
+		if args := specDefault(fs.Default); len(args) > 0 {
+			fmt.Fprintf(&buf, " // default: %s", strings.Join(args, ", "))
+		}
+		fmt.Fprintln(&buf)
+	}
+	fmt.Fprintln(&buf, "}")
+
+	_, err = w.Write(buf.Bytes())
+	return
+}
+
+var specBasicTypes = []reflect.Type{
+	reflect.TypeOf(""), reflect.TypeOf(false),
+	reflect.TypeOf(int(0)), reflect.TypeOf(int8(0)), reflect.TypeOf(int16(0)), reflect.TypeOf(int32(0)), reflect.TypeOf(int64(0)),
+	reflect.TypeOf(uint(0)), reflect.TypeOf(uint8(0)), reflect.TypeOf(uint16(0)), reflect.TypeOf(uint32(0)), reflect.TypeOf(uint64(0)),
+	reflect.TypeOf(float32(0)), reflect.TypeOf(float64(0)),
+	reflect.TypeOf(time.Duration(0)), reflect.TypeOf(time.Time{}), reflect.TypeOf(net.IP{}),
+}
+
+// 按照帮助信息中显示的类型名查找类型，如 int, duration, strings
+func specType(name string) (reflect.Type, error) {
+	if name == "" {
+		name = "string"
+	}
+
+	candidates := append([]reflect.Type{}, specBasicTypes...)
+	for t := range extends {
+		candidates = append(candidates, t)
+	}
+
+	for _, t := range candidates {
+		if rType(t) == name {
+			return t, nil
+		}
+		if st := reflect.SliceOf(t); rType(st) == name {
+			return st, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown flag type: %s", name)
+}
+
+func specDefault(def any) (args []string) {
+	switch d := def.(type) {
+	case nil:
+	case []any:
+		for _, it := range d {
+			args = append(args, fmt.Sprint(it))
+		}
+	default:
+		args = append(args, fmt.Sprint(d))
+	}
+	return
+}
+
+// max-conn => MaxConn
+func goName(name string) string {
+	var sb strings.Builder
+	for _, s := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		sb.WriteString(strings.ToUpper(s[:1]) + s[1:])
+	}
+	return sb.String()
+}
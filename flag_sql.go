@@ -0,0 +1,155 @@
+package flags
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 从数据库读取配置，数据库驱动由程序自己导入
+//
+// query 返回两列时每一行为参数名和值，如 `SELECT name, value FROM config WHERE app = 'web'`；
+// 返回一列时为 JSON 文档，嵌套的对象以 `.` 连接作为参数名。未定义的参数和来自命令行、环境变量的参数会忽略
+func LoadSQL(ctx context.Context, db *sql.DB, query string, flags ...*FlagSet) error {
+	set := flagSet(flags)
+	values, err := querySQL(ctx, db, query)
+	if err != nil {
+		return err
+	}
+	return SetAll(set, configValues(set, values), SourceConfig)
+}
+
+// 每隔 interval 重新读取一次配置，内容变化时写入参数，ctx 取消后停止，同 WatchSQLPoll
+//
+// interval 不是正数时 panic
+func WatchSQL(ctx context.Context, db *sql.DB, query string, interval time.Duration, onError func(error), flags ...*FlagSet) {
+	if err := WatchSQLPoll(ctx, db, query, Poll{Interval: interval}, onError, flags...); err != nil {
+		panic(err)
+	}
+}
+
+// 立即读取一次配置，之后按 poll 的设置重新读取，内容变化时写入参数，ctx 取消后停止
+//
+// 第一次之后的变化写入后调用 OnReload 注册的函数。轮询时读取、写入或者 OnReload 出错时调用 onError，
+// onError 可以为 nil，Refresh 时返回错误
+func WatchSQLPoll(ctx context.Context, db *sql.DB, query string, poll Poll, onError func(error), flags ...*FlagSet) error {
+	if err := poll.check(); err != nil {
+		return err
+	}
+
+	set := flagSet(flags)
+	r := &remoteReload{set: set}
+	refresh := addWatcher(ctx, set, func(ctx context.Context) error {
+		values, err := querySQL(ctx, db, query)
+		if err != nil {
+			return err
+		}
+		return r.apply(ctx, values)
+	})
+	report := func() {
+		if err := refresh(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+
+	go func() {
+		report()
+		poll.run(ctx, report)
+	}()
+	return nil
+}
+
+func querySQL(ctx context.Context, db *sql.DB, query string) (values map[string]string, err error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return
+	}
+	if len(cols) != 1 && len(cols) != 2 {
+		return nil, fmt.Errorf("config query must return 1 (json) or 2 (name, value) columns; got %d", len(cols))
+	}
+
+	values = map[string]string{}
+	for rows.Next() {
+		switch len(cols) {
+		case 1:
+			var doc string
+			if err = rows.Scan(&doc); err != nil {
+				return
+			}
+			var v any
+			if err = json.Unmarshal([]byte(doc), &v); err != nil {
+				return nil, fmt.Errorf("invalid json config: %w", err)
+			}
+			flattenJSON("", v, values)
+		case 2:
+			var name, val sql.NullString
+			if err = rows.Scan(&name, &val); err != nil {
+				return
+			}
+			values[name.String] = val.String
+		}
+	}
+	return values, rows.Err()
+}
+
+// 展开 JSON，对象的键以 `.` 连接，数组以 `,` 连接
+func flattenJSON(prefix string, v any, out map[string]string) {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, it := range x {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			flattenJSON(k, it, out)
+		}
+	case []any:
+		items := make([]string, 0, len(x))
+		for _, it := range x {
+			items = append(items, fmt.Sprint(it))
+		}
+		out[prefix] = strings.Join(items, ",")
+	case nil:
+		out[prefix] = ""
+	default:
+		out[prefix] = fmt.Sprint(x)
+	}
+}
+
+// 远程配置中可以写入的值，忽略未定义的参数，和来源优先级高于配置文件(命令行、环境变量等)的参数
+func configValues(set *FlagSet, values map[string]string) map[string]string {
+	values = definedValues(set, values)
+	for name := range values {
+		f := set.Lookup(name)
+		if f.Changed {
+			delete(values, name)
+			continue
+		}
+		if v, ok := f.Value.(*value); ok {
+			v.mu.Lock()
+			src := v.source
+			v.mu.Unlock()
+			if outranksConfig(src) {
+				delete(values, name)
+			}
+		}
+	}
+	return values
+}
+
+func definedValues(set *FlagSet, values map[string]string) map[string]string {
+	for name := range values {
+		if set.Lookup(name) == nil {
+			delete(values, name)
+		}
+	}
+	return values
+}
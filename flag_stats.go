@@ -0,0 +1,60 @@
+package flags
+
+import (
+	"sync"
+	"time"
+)
+
+// 解析的耗时和各来源设置的参数个数，用于排查大配置、远程配置源导致的启动慢
+type ParseStats struct {
+	Duration time.Duration            // 最近一次解析的总耗时
+	Loads    map[Source]time.Duration // 各来源的累计加载耗时，环境变量和 Downward API 在绑定时读取，配置文件、元数据和交互输入在解析时读取
+	Counts   map[Source]int           // 当前值来自各来源的参数个数
+}
+
+type parseStats struct {
+	mu       sync.Mutex
+	duration time.Duration
+	loads    map[Source]time.Duration
+}
+
+// 记录从 start 开始的加载耗时，用法: defer meta.stats.load(SourceConfig, time.Now())
+func (s *parseStats) load(src Source, start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loads == nil {
+		s.loads = map[Source]time.Duration{}
+	}
+	s.loads[src] += time.Since(start)
+}
+
+func (s *parseStats) done(start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.duration = time.Since(start)
+}
+
+// 解析的统计信息，在 ParseFlags 之后调用
+func Stats(flags ...*FlagSet) (stats ParseStats) {
+	meta := metaOf(flagSet(flags))
+
+	meta.stats.mu.Lock()
+	stats.Duration = meta.stats.duration
+	stats.Loads = make(map[Source]time.Duration, len(meta.stats.loads))
+	for src, d := range meta.stats.loads {
+		stats.Loads[src] = d
+	}
+	meta.stats.mu.Unlock()
+
+	stats.Counts = map[Source]int{}
+	for _, field := range meta.fieldList() {
+		v := field.Value
+		v.mu.Lock()
+		stats.Counts[v.source]++
+		v.mu.Unlock()
+	}
+	return
+}
+
+// 同 Stats
+func (s *Set) Stats() ParseStats { return Stats(s.FlagSet) }
@@ -0,0 +1,89 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// 未定义参数的错误后面附加最接近的参数名: unknown flag: --listn, did you mean --listen?
+func suggestFlags(set *FlagSet, err error) error {
+	name, ok := strings.CutPrefix(err.Error(), "unknown flag: --")
+	if !ok {
+		return err
+	}
+
+	if names := similarFlags(set, name); len(names) > 0 {
+		return fmt.Errorf("%w, did you mean --%s?", err, strings.Join(names, " or --"))
+	}
+	return err
+}
+
+// 未定义参数时输出帮助信息，最后提示最接近的参数名
+func usageSuggest(out io.Writer, set *FlagSet, err error) {
+	name, ok := strings.CutPrefix(err.Error(), "unknown flag: --")
+	if !ok {
+		return
+	}
+
+	set.Usage()
+	if names := similarFlags(set, name); len(names) > 0 {
+		fmt.Fprintf(out, "did you mean --%s?\n", strings.Join(names, " or --"))
+	}
+}
+
+// 编辑距离最小(不超过名称长度的三分之一，至少为 2)的参数，最多 3 个
+func similarFlags(set *FlagSet, name string) (names []string) {
+	limit := len(name) / 3
+	if limit < 2 {
+		limit = 2
+	}
+
+	best := limit + 1
+	set.VisitAll(func(f *Flag) {
+		if f.Hidden || f.Deprecated != "" {
+			return
+		}
+		switch d := levenshtein(name, f.Name); {
+		case d < best:
+			best, names = d, []string{f.Name}
+		case d == best:
+			names = append(names, f.Name)
+		}
+	})
+
+	sort.Strings(names)
+	if len(names) > 3 {
+		names = names[:3]
+	}
+	return
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
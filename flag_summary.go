@@ -0,0 +1,67 @@
+package flags
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 超过这个长度时 SummaryBanner 分多行输出
+const summaryWidth = 120
+
+// 启动时用于记录日志的配置摘要，如 `app 1.0.0: port=8080 host=db timeout=1m`
+//
+// 有字段标记了 `summary:"true"` 时只包含这些字段，否则包含所有不隐藏的字段，
+// 敏感参数不会出现在摘要中，太长时每行以两个空格缩进分多行输出
+func SummaryBanner(flags ...*FlagSet) string {
+	set := flagSet(flags)
+	meta := metaOf(set)
+
+	var marked bool
+	for _, field := range meta.fieldList() {
+		if marked = field.Value.summary; marked {
+			break
+		}
+	}
+
+	var items []string
+	set.VisitAll(func(f *Flag) {
+		field := meta.field(f.Name)
+		switch {
+		case field == nil, isSecret(set, f):
+			return
+		case marked && !field.Value.summary, !marked && f.Hidden:
+			return
+		}
+
+		s := strings.Join(field.Value.current(), ",")
+		if s == "" || strings.ContainsAny(s, " \t\n\"") {
+			s = strconv.Quote(s)
+		}
+		items = append(items, f.Name+"="+s)
+	})
+
+	head := nameOf(set)
+	if version := versionOf(set); version != "" {
+		head += " " + version
+	}
+
+	if line := head + ": " + strings.Join(items, " "); len(line) <= summaryWidth {
+		return line
+	}
+
+	var sb strings.Builder
+	sb.WriteString(head + ":")
+	width := summaryWidth
+	for _, item := range items {
+		if width+1+len(item) > summaryWidth {
+			sb.WriteString("\n ")
+			width = 1
+		}
+		sb.WriteString(" " + item)
+		width += 1 + len(item)
+	}
+	return sb.String()
+}
+
+// 同 SummaryBanner
+func (s *Set) SummaryBanner() string { return SummaryBanner(s.FlagSet) }
@@ -0,0 +1,72 @@
+package flags
+
+import (
+	"sort"
+	"strings"
+)
+
+// 返回所有参数的当前值，以参数名为键，切片的多个值以 `,` 连接
+func Values(flags ...*FlagSet) map[string]string {
+	values := map[string]string{}
+	flagSet(flags).VisitAll(func(f *Flag) { values[f.Name] = currentValue(f) })
+	return values
+}
+
+// 同 Values，参数名按 `.` 拆分为嵌套的 map，如 `db.host` 对应 {"db": {"host": ...}}
+func NestedValues(flags ...*FlagSet) map[string]any { return nest(Values(flags...)) }
+
+// 参数名按 `.` 拆分为嵌套的 map，按参数名排序处理，结果和 map 的遍历顺序无关
+//
+// 参数名和其他参数名的前缀冲突时(同时有 `db` 和 `db.host`)，这些参数都保留完整的参数名
+func nest[V any](flat map[string]V) map[string]any {
+	names := make([]string, 0, len(flat))
+	keep := map[string]bool{}
+	for name := range flat {
+		names = append(names, name)
+		for i := 0; i < len(name); i++ {
+			if _, ok := flat[name[:i]]; ok && name[i] == '.' {
+				keep[name[:i]], keep[name] = true, true
+			}
+		}
+	}
+	sort.Strings(names)
+
+	values := map[string]any{}
+	for _, name := range names {
+		keys := strings.Split(name, ".")
+		if keep[name] || len(keys) == 1 {
+			values[name] = flat[name]
+			continue
+		}
+
+		m := values
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := m[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				m[key] = child
+			}
+			m = child
+		}
+		m[keys[len(keys)-1]] = flat[name]
+	}
+	return values
+}
+
+// 将程序中直接对结构体的修改同步到参数中，使 Values 等读取到的值与结构体一致
+func Sync(flags ...*FlagSet) {
+	flagSet(flags).VisitAll(func(f *Flag) {
+		if v, ok := f.Value.(*value); ok {
+			v.mu.Lock()
+			v.sync()
+			v.mu.Unlock()
+		}
+	})
+}
+
+func currentValue(f *Flag) string {
+	if v, ok := f.Value.(*value); ok {
+		return strings.Join(v.current(), ",")
+	}
+	return f.Value.String()
+}
@@ -0,0 +1,114 @@
+package flags
+
+import (
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"text/template"
+)
+
+var buildTime string
+
+// 测试时替换，模拟不同的编译信息
+var readBuildInfo = debug.ReadBuildInfo
+
+// 设置或者获取编译时间，参数规则同 Version
+func BuildTime(updateTime ...string) string {
+	for _, t := range updateTime {
+		if t != "" {
+			buildTime = t
+		}
+	}
+	return buildTime
+}
+
+// 版本信息，用于渲染 --version 的输出
+type VersionInfo struct {
+	Name      string
+	Version   string
+	BuildTime string
+	GoVersion string
+	Revision  string
+	Dirty     bool
+}
+
+const DefaultVersionTemplate = `{{.Name}} -- version {{.Version}}
+{{- with .BuildTime}}
+build time: {{.}}{{end}}
+{{- with .Revision}}
+revision:   {{.}}{{if $.Dirty}} (dirty){{end}}{{end}}
+go version: {{.GoVersion}}
+`
+
+var versionTemplate = template.Must(template.New("version").Parse(DefaultVersionTemplate))
+
+// 自定义 --version 的输出模板，模板数据为 VersionInfo
+func VersionTemplate(text string) (err error) {
+	tpl, err := template.New("version").Parse(text)
+	if err == nil {
+		versionTemplate = tpl
+	}
+	return
+}
+
+func versionInfo(name, version string) VersionInfo {
+	info := VersionInfo{Name: name, Version: version, BuildTime: buildTime, GoVersion: runtime.Version()}
+	info.Revision, info.Dirty, _ = vcsInfo()
+	return info
+}
+
+func vcsInfo() (revision string, dirty bool, time string) {
+	if bi, ok := readBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			case "vcs.time":
+				time = s.Value
+			}
+		}
+	}
+	return
+}
+
+// 从编译时嵌入的模块信息中读取版本号和编译时间，已经设置的值不会被覆盖
+func VersionFromBuildInfo() {
+	bi, ok := readBuildInfo()
+	if !ok {
+		return
+	}
+
+	if version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		version = bi.Main.Version
+	}
+
+	if buildTime == "" {
+		_, _, buildTime = vcsInfo()
+	}
+
+	if version == "" {
+		if revision, dirty, _ := vcsInfo(); revision != "" {
+			if version = revision; len(version) > 12 {
+				version = version[:12]
+			}
+			if dirty {
+				version += "-dirty"
+			}
+		}
+	}
+}
+
+func writeVersion(out io.Writer, name, version string) error {
+	return versionTemplate.Execute(out, versionInfo(name, version))
+}
+
+// 返回 --version 的输出内容，不输出也不退出
+func RenderVersion(flags ...*FlagSet) string {
+	set := flagSet(flags)
+	var buf strings.Builder
+	writeVersion(&buf, nameOf(set), versionOf(set))
+	return buf.String()
+}
@@ -0,0 +1,87 @@
+package flags
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// 参数的完整信息，用于在此之上生成文档、界面等
+type ItemInfo struct {
+	Name                string
+	Shorthand           string
+	Usage               string
+	Type                string   // 帮助信息中显示的类型名，bool 类型为空
+	Env                 []string // 读取的环境变量，`*` 开头的已过期
+	Groups              []string // 所在的参数组，如 `--a, --b: mutually exclusive`
+	Example             string   // `example` 标签的示例值
+	Owner               string   // 绑定的结构体字段，如 main.Config.Port
+	Hidden              bool
+	Deprecated          string
+	ShorthandDeprecated string
+	Secret              bool   // 敏感参数的 Default 和 Current 为 ******
+	Source              Source // 当前值的来源
+	SourceKey           string
+	Default             string
+	Current             string
+	Flag                *Flag
+}
+
+// 按定义的顺序遍历所有参数的信息，包括隐藏的参数
+func VisitItems(fn func(it *ItemInfo), flags ...*FlagSet) {
+	set := flagSet(flags)
+	meta := metaOf(set)
+
+	set.VisitAll(func(f *Flag) {
+		it := &ItemInfo{
+			Name:                f.Name,
+			Shorthand:           f.Shorthand,
+			Hidden:              f.Hidden,
+			Deprecated:          f.Deprecated,
+			ShorthandDeprecated: f.ShorthandDeprecated,
+			Secret:              isSecret(set, f),
+			Default:             f.DefValue,
+			Current:             currentValue(f),
+			Owner:               meta.owners[f.Name],
+			Flag:                f,
+		}
+		// 同帮助信息，说明中 `name` 指定的名称优先
+		it.Type, it.Usage = pflag.UnquoteUsage(f)
+		if name, translated := typeName(f); translated && !strings.Contains(f.Usage, "`") {
+			it.Type = name
+		}
+		it.Source, it.SourceKey = FlagSource(f.Name, set)
+
+		if field := meta.field(f.Name); field != nil {
+			it.Env = append([]string(nil), field.Env...)
+			it.Example = getTag(field.Field.Tag, _TAG_EXAMPLE)
+			if it.Usage = field.Usage; it.Usage == "" {
+				it.Usage = field.Field.Name
+			}
+		}
+
+		for _, g := range meta.groups {
+			for _, name := range g.names {
+				if name == f.Name {
+					it.Groups = append(it.Groups, g.String())
+					break
+				}
+			}
+		}
+
+		if it.Secret {
+			it.Default, it.Current = redactValue(it.Default), redactValue(it.Current)
+		}
+		if it.Default == "[]" {
+			it.Default = ""
+		}
+		if strings.TrimSpace(it.Current) == "" {
+			it.Current = ""
+		}
+
+		fn(it)
+	})
+}
+
+// 同 VisitItems，遍历参数的完整信息，pflag 原来的 VisitAll 通过 s.FlagSet.VisitAll 调用
+func (s *Set) VisitAll(fn func(it *ItemInfo)) { VisitItems(fn, s.FlagSet) }
@@ -0,0 +1,17 @@
+module github.com/cnk3x/flags/protoflag
+
+go 1.20
+
+require (
+	github.com/cnk3x/flags v0.0.0-00010101000000-000000000000
+	github.com/spf13/pflag v1.0.5
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	github.com/BurntSushi/toml v1.4.0 // indirect
+	gopkg.in/ini.v1 v1.67.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/cnk3x/flags => ../
@@ -0,0 +1,186 @@
+// protobuf 消息字段绑定为命令行参数，便于调试时从命令行构造请求消息
+package protoflag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cnk3x/flags"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// 将消息的字段注册为参数，参数名为 proto 字段名，嵌套的消息以 `.` 连接，如 `--source_context.file_name`
+//
+// 字段的注释作为参数说明，不支持 map 和 repeated message 字段。
+// 递归引用的消息(如 `message Node { Node parent = 1; }`)只展开一层，再次出现时跳过
+func Bind(msg proto.Message, set *flags.FlagSet) error {
+	m := msg.ProtoReflect()
+	return bind(set, "", func() protoreflect.Message { return m }, func() protoreflect.Message { return m }, m.Descriptor(), map[protoreflect.FullName]bool{})
+}
+
+// binding 为正在展开的消息，避免递归引用的消息无限展开
+func bind(set *flags.FlagSet, prefix string, mutable, get func() protoreflect.Message, md protoreflect.MessageDescriptor, binding map[protoreflect.FullName]bool) (err error) {
+	binding[md.FullName()] = true
+	defer delete(binding, md.FullName())
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := prefix + fd.TextName()
+
+		switch {
+		case fd.IsMap(), fd.IsList() && fd.Kind() == protoreflect.MessageKind:
+			continue
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			if binding[fd.Message().FullName()] {
+				continue
+			}
+			childMutable := func() protoreflect.Message { return mutable().Mutable(fd).Message() }
+			childGet := func() protoreflect.Message {
+				if p := get(); p != nil && p.Has(fd) {
+					return p.Get(fd).Message()
+				}
+				return nil
+			}
+			if err = bind(set, name+".", childMutable, childGet, fd.Message(), binding); err != nil {
+				return
+			}
+			continue
+		}
+
+		v := &fieldValue{mutable: mutable, get: get, fd: fd}
+		f := set.VarPF(v, name, "", usage(fd))
+		if fd.Kind() == protoreflect.BoolKind && !fd.IsList() {
+			f.NoOptDefVal = "true"
+		}
+	}
+	return
+}
+
+func usage(fd protoreflect.FieldDescriptor) string {
+	loc := fd.ParentFile().SourceLocations().ByDescriptor(fd)
+	if s := strings.TrimSpace(loc.LeadingComments); s != "" {
+		return strings.Join(strings.Fields(s), " ")
+	}
+	if s := strings.TrimSpace(loc.TrailingComments); s != "" {
+		return strings.Join(strings.Fields(s), " ")
+	}
+	return string(fd.FullName())
+}
+
+type fieldValue struct {
+	mutable func() protoreflect.Message
+	get     func() protoreflect.Message
+	fd      protoreflect.FieldDescriptor
+	changed bool
+}
+
+func (v *fieldValue) String() string {
+	m := v.get()
+	if m == nil || !m.Has(v.fd) {
+		return ""
+	}
+
+	if v.fd.IsList() {
+		list := m.Get(v.fd).List()
+		items := make([]string, list.Len())
+		for i := range items {
+			items[i] = format(v.fd, list.Get(i))
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	}
+	return format(v.fd, m.Get(v.fd))
+}
+
+func (v *fieldValue) Type() string {
+	t := v.fd.Kind().String()
+	if v.fd.IsList() {
+		t += "s"
+	}
+	return t
+}
+
+func (v *fieldValue) Set(s string) (err error) {
+	pv, err := parse(v.fd, s)
+	if err != nil {
+		return
+	}
+
+	m := v.mutable()
+	if v.fd.IsList() {
+		list := m.Mutable(v.fd).List()
+		if !v.changed {
+			list.Truncate(0)
+		}
+		list.Append(pv)
+	} else {
+		m.Set(v.fd, pv)
+	}
+	v.changed = true
+	return
+}
+
+func format(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.BytesKind:
+		return string(v.Bytes())
+	default:
+		return v.String()
+	}
+}
+
+func parse(fd protoreflect.FieldDescriptor, s string) (v protoreflect.Value, err error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var n int64
+		n, err = strconv.ParseInt(s, 0, 32)
+		v = protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var n int64
+		n, err = strconv.ParseInt(s, 0, 64)
+		v = protoreflect.ValueOfInt64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var n uint64
+		n, err = strconv.ParseUint(s, 0, 32)
+		v = protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var n uint64
+		n, err = strconv.ParseUint(s, 0, 64)
+		v = protoreflect.ValueOfUint64(n)
+	case protoreflect.FloatKind:
+		var f float64
+		f, err = strconv.ParseFloat(s, 32)
+		v = protoreflect.ValueOfFloat32(float32(f))
+	case protoreflect.DoubleKind:
+		var f float64
+		f, err = strconv.ParseFloat(s, 64)
+		v = protoreflect.ValueOfFloat64(f)
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(s)
+	case protoreflect.BytesKind:
+		v = protoreflect.ValueOfBytes([]byte(s))
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		if ev := values.ByName(protoreflect.Name(s)); ev != nil {
+			v = protoreflect.ValueOfEnum(ev.Number())
+		} else if n, e := strconv.ParseInt(s, 10, 32); e == nil && values.ByNumber(protoreflect.EnumNumber(n)) != nil {
+			v = protoreflect.ValueOfEnum(protoreflect.EnumNumber(n))
+		} else {
+			err = fmt.Errorf("unknown %s value: %s", fd.Enum().Name(), s)
+		}
+	default:
+		err = fmt.Errorf("unsupported field kind: %s", fd.Kind())
+	}
+	return
+}
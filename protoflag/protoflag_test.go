@@ -0,0 +1,87 @@
+package protoflag
+
+import (
+	"testing"
+
+	"github.com/cnk3x/flags"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestBind(t *testing.T) {
+	var msg typepb.Type
+
+	set := pflag.NewFlagSet("proto", pflag.ContinueOnError)
+	if err := Bind(&msg, set); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"--name", "Pet", "--oneofs", "a", "--oneofs", "b", "--source_context.file_name", "pet.proto", "--syntax", "SYNTAX_PROTO3"}
+	if err := flags.ParseFlags(set, args); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Name != "Pet" || len(msg.Oneofs) != 2 || msg.GetSourceContext().GetFileName() != "pet.proto" || msg.Syntax != typepb.Syntax_SYNTAX_PROTO3 {
+		t.Errorf("msg = %v", &msg)
+	}
+}
+
+func TestBindRecursive(t *testing.T) {
+	// message Node { Node parent = 1; string name = 2; Leaf leaf = 3; }  message Leaf { Node root = 1; int32 id = 2; }
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name: proto.String(name), JsonName: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(),
+			Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name: proto.String("node.proto"), Package: proto.String("test"), Syntax: proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Node"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("parent", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Node"),
+				field("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("leaf", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Leaf"),
+			}},
+			{Name: proto.String("Leaf"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("root", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Node"),
+				field("id", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+			}},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := dynamicpb.NewMessage(fd.Messages().ByName("Node"))
+	set := pflag.NewFlagSet("proto", pflag.ContinueOnError)
+	if err := Bind(msg, set); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"name", "leaf.id"} {
+		if set.Lookup(name) == nil {
+			t.Errorf("--%s not defined", name)
+		}
+	}
+	for _, name := range []string{"parent.name", "leaf.root.name"} {
+		if set.Lookup(name) != nil {
+			t.Errorf("--%s should not be expanded", name)
+		}
+	}
+
+	if err := flags.ParseFlags(set, []string{"--name", "a", "--leaf.id", "7"}); err != nil {
+		t.Fatal(err)
+	}
+	leaf := msg.Get(fd.Messages().ByName("Node").Fields().ByName("leaf")).Message()
+	if got := leaf.Get(leaf.Descriptor().Fields().ByName("id")).Int(); got != 7 {
+		t.Errorf("leaf.id = %d", got)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type FlagField struct {
@@ -17,9 +19,14 @@ type FlagField struct {
 	Env             []string
 	Deprecated      string
 	ShortDeprecated string
+	Hidden          bool
+	NoOptDefVal     string   // 只写参数名不写值时使用的值，如 `--cache` 等同于 `--cache=memory`
+	Aliases         []string // 旧的参数名，使用时提示改用新的参数名
 
 	Struct  reflect.Value
 	Referer reflect.Value
+
+	prefix string // WithPrefix 设置的前缀，用于分组标签和依赖的参数名
 }
 
 func (f *FlagField) UpdateFromEnv() {
@@ -35,9 +42,9 @@ func (f *FlagField) UpdateFromEnv() {
 			}
 
 			if ak != "" {
-				fmt.Fprintf(os.Stderr, "[WARN] 环境变量参数[%s]已过期,请使用[%s]替代", ck, ak)
+				fmt.Fprintf(os.Stderr, "[WARN] 环境变量参数[%s]已过期,请使用[%s]替代\n", ck, ak)
 			} else {
-				fmt.Fprintf(os.Stderr, "[WARN] 环境变量参数[%s]已过期", ck)
+				fmt.Fprintf(os.Stderr, "[WARN] 环境变量参数[%s]已过期\n", ck)
 			}
 		}
 	}
@@ -55,8 +62,12 @@ func (f *FlagField) UpdateFromEnv() {
 			if !deprecated && ak == "" {
 				ak = ck
 			}
-			if ev := os.Getenv(ck); ev != "" {
+			if ev := getenv(ck); ev != "" {
+				base, src, key := f.Value.Args(), f.Value.source, f.Value.sourceKey
 				if e := f.Value.SetDefault(ev); e == nil {
+					f.Value.envBase, f.Value.envBaseSource, f.Value.envBaseKey = base, src, key
+					f.Value.setSource(envSource(ck), ck)
+					f.Value.envKey, f.Value.envVal = ck, ev
 					printDeprecatedEnvKey(f.Env, ck, ak, deprecated, i)
 					return
 				}
@@ -66,13 +77,20 @@ func (f *FlagField) UpdateFromEnv() {
 }
 
 func ParseStruct(src any, checkSetable ...bool) (items []*FlagField, err error) {
-	r := rVal(src)
+	r, err := structValue(src)
+	if err != nil {
+		return
+	}
 
 	if len(checkSetable) > 0 && checkSetable[0] && !r.CanSet() {
 		err = fmt.Errorf("can't set %T", src)
 		return
 	}
 
+	return parseStruct(r, "")
+}
+
+func parseStruct(r reflect.Value, prefix string) (items []*FlagField, err error) {
 	for i, t := 0, r.Type(); i < t.NumField(); i++ {
 		f := t.Field(i)
 
@@ -81,7 +99,15 @@ func ParseStruct(src any, checkSetable ...bool) (items []*FlagField, err error)
 		}
 
 		if f.Anonymous {
-			children, e := ParseStruct(r.Field(i), checkSetable...)
+			fv := r.Field(i)
+			if fv.Kind() == reflect.Pointer && fv.IsNil() {
+				if !fv.CanSet() {
+					continue
+				}
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+
+			children, e := parseStruct(reflect.Indirect(fv), prefix)
 			if e != nil {
 				err = e
 				return
@@ -90,11 +116,38 @@ func ParseStruct(src any, checkSetable ...bool) (items []*FlagField, err error)
 			continue
 		}
 
+		// 匿名的内联结构体，以字段名作为参数名前缀: Opts struct{ A string } => --opts.a
+		if f.Type.Kind() == reflect.Struct && f.Type.Name() == "" {
+			flagTag := getTag(f.Tag, _TAG_FLAG)
+			if flagTag == "-" {
+				continue
+			}
+
+			name := strings.ToLower(f.Name)
+			if tags := fieldSpilt(flagTag); len(tags) > 0 {
+				name = tags[0]
+			}
+
+			children, e := parseStruct(r.Field(i), prefix+name+".")
+			if e != nil {
+				err = e
+				return
+			}
+			items = append(items, children...)
+			continue
+		}
+
+		// 结构体切片由 structSlices 处理，通过 --name.0.field 的形式设置
+		if isStructSlice(f.Type) {
+			continue
+		}
+
 		if !isAllow(f.Type) {
 			return
 		}
 
 		if item, ignored := parseField(r, f, i); !ignored {
+			item.Name = prefix + item.Name
 			items = append(items, &item)
 		}
 	}
@@ -102,6 +155,29 @@ func ParseStruct(src any, checkSetable ...bool) (items []*FlagField, err error)
 	return
 }
 
+// 取得结构体的值，src 可以是结构体、结构体指针或者对应的 reflect.Value
+func structValue(src any) (r reflect.Value, err error) {
+	if src == nil {
+		return r, fmt.Errorf("pass a non-nil pointer to a struct; got nil")
+	}
+
+	if r = rVal(src); r.Kind() == reflect.Pointer {
+		if r.IsNil() {
+			return r, fmt.Errorf("pass a non-nil pointer to a struct; got nil %s", r.Type())
+		}
+		r = r.Elem()
+	}
+
+	if !r.IsValid() || r.Kind() != reflect.Struct {
+		var t any = r
+		if r.IsValid() {
+			t = r.Type()
+		}
+		return r, fmt.Errorf("pass a non-nil pointer to a struct; got %v", t)
+	}
+	return
+}
+
 func parseField(r reflect.Value, f reflect.StructField, i int) (item FlagField, ignored bool) {
 	if flagTag := getTag(f.Tag, _TAG_FLAG); flagTag != "" {
 		if ignored = flagTag == "-"; ignored {
@@ -124,7 +200,7 @@ func parseField(r reflect.Value, f reflect.StructField, i int) (item FlagField,
 	}
 
 	if deprecatedTag := getTag(f.Tag, _TAG_DEPRECATED); deprecatedTag != "" {
-		nn := fieldSpilt(deprecatedTag)
+		nn := listSplit(deprecatedTag)
 		for _, n := range nn {
 			if n != "" {
 				if item.Deprecated == "" {
@@ -139,6 +215,10 @@ func parseField(r reflect.Value, f reflect.StructField, i int) (item FlagField,
 		}
 	}
 
+	item.Hidden = tagBool(f.Tag, _TAG_HIDDEN)
+	item.NoOptDefVal = getTag(f.Tag, _TAG_NOOPTDEFVAL)
+	item.Aliases = fieldSpilt(getTag(f.Tag, _TAG_ALIAS))
+
 	if envTag := getTag(f.Tag, _TAG_ENV); envTag != "" && envTag != "-" {
 		item.Env = append(item.Env, fieldSpilt(envTag)...)
 	}
@@ -152,6 +232,19 @@ func parseField(r reflect.Value, f reflect.StructField, i int) (item FlagField,
 	item.Referer = r.Field(i)
 	item.Usage = getTag(f.Tag, _TAG_USAGE)
 	item.Value = newValue(item.Referer, f.Type)
+	item.Value.rules = parseRules(getTag(f.Tag, _TAG_VALIDATE))
+	if schemes := fieldSpilt(getTag(f.Tag, _TAG_SCHEMES)); len(schemes) > 0 {
+		item.Value.rules = append(item.Value.rules, rule{name: "schemes", arg: strings.ToLower(strings.Join(schemes, " "))})
+	}
+	fieldLayouts(&item)
+	fieldByteSize(&item)
+	fieldPath(&item)
+	fieldEncoding(&item)
+	item.Value.omitempty = getTag(f.Tag, _TAG_MERGE) == "omitempty"
+	item.Value.envFirst = fieldPriority(f.Tag) == "env"
+	item.Value.secret = tagBool(f.Tag, _TAG_SECRET)
+	item.Value.summary = tagBool(f.Tag, _TAG_SUMMARY)
+	item.Value.count = tagBool(f.Tag, _TAG_COUNT) && isIntKind(item.Value.DirectType().Kind())
 	return
 }
 
@@ -160,20 +253,87 @@ const (
 	_TAG_DEPRECATED = "deprecated"
 	_TAG_ENV        = "env"
 	_TAG_USAGE      = "usage"
+	_TAG_EXAMPLE    = "example"
+	_TAG_HIDDEN     = "hidden"
+	_TAG_VALIDATE   = "validate"
+	_TAG_COUNT      = "count"
+	_TAG_NEGATABLE  = "negatable"
+	_TAG_MERGE      = "merge"
+	_TAG_ALIAS      = "alias"
+
+	_TAG_NOOPTDEFVAL = "nooptdefval"
+	_TAG_LAYOUT      = "layout"
+	_TAG_SIZE        = "size"
+	_TAG_SCHEMES     = "schemes"
+	_TAG_PATH        = "path"
+	_TAG_PRIORITY    = "priority"
+	_TAG_ENCODING    = "encoding"
+	_TAG_DOWNWARD    = "downward"
+	_TAG_METADATA    = "metadata"
+	_TAG_SECRET      = "secret"
+	_TAG_PROMPT      = "prompt"
+	_TAG_SUMMARY     = "summary"
+
+	_TAG_DEFAULT_FROM       = "defaultFrom"
+	_TAG_DEFAULT_FROM_ALIAS = "defaultfrom"
+
+	_TAG_EXCLUSIVE    = "exclusive"
+	_TAG_ONE_REQUIRED = "onerequired"
+	_TAG_REQUIRES     = "requires"
 )
 
 var (
-	fieldSpilt = func(s string) []string {
-		fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == '|' || unicode.IsSpace(r) })
-		var x int
-		for _, s := range fields {
-			if s = strings.Trim(s, "-_"); s != "" {
-				fields[x] = s
-				x++
+	// 按 `,;|` 和空白分隔，去掉两端的 `-` 和 `_`，用于参数名、环境变量名类的标签
+	fieldSpilt = func(s string) []string { return tokenize(s, true) }
+
+	// 只按 `,;|` 分隔，保留空格和 `-`，用于说明文字类的标签
+	listSplit = func(s string) []string { return tokenize(s, false) }
+
+	getTag = func(tag reflect.StructTag, tagName string) string { return strings.TrimSpace(tag.Get(tagName)) }
+
+	tagBool = func(tag reflect.StructTag, tagName string) bool {
+		if s := getTag(tag, tagName); s != "" {
+			b, _ := strconv.ParseBool(s)
+			return b
+		}
+		return false
+	}
+)
+
+// 所有列表类标签共用的分词，一次遍历，只分配一次结果切片
+//
+// names 为 true 时空白也作为分隔符，并去掉每一段两端的 `-` 和 `_`，否则只去掉两端的空白
+func tokenize(s string, names bool) (out []string) {
+	isSep := func(r rune) bool { return r == ',' || r == ';' || r == '|' || (names && unicode.IsSpace(r)) }
+
+	n := 1
+	for _, r := range s {
+		if isSep(r) {
+			n++
+		}
+	}
+
+	add := func(tok string) {
+		if names {
+			tok = strings.Trim(tok, "-_")
+		} else {
+			tok = strings.TrimSpace(tok)
+		}
+		if tok != "" {
+			if out == nil {
+				out = make([]string, 0, n)
 			}
+			out = append(out, tok)
 		}
-		return fields[:x]
 	}
 
-	getTag = func(tag reflect.StructTag, tagName string) string { return strings.TrimSpace(tag.Get(tagName)) }
-)
+	start := 0
+	for i, r := range s {
+		if isSep(r) {
+			add(s[start:i])
+			start = i + utf8.RuneLen(r)
+		}
+	}
+	add(s[start:])
+	return
+}
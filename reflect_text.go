@@ -0,0 +1,42 @@
+package flags
+
+import (
+	"encoding"
+	"reflect"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// 实现了 encoding.TextUnmarshaler 的类型，如 uuid.UUID，扩展类型优先
+func isText(t reflect.Type) bool {
+	return t.Kind() != reflect.Pointer && !HasExtend(t) && reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func rSetText(v reflect.Value, s string) error {
+	if !v.CanAddr() {
+		return invalid("rSetText")
+	}
+
+	tmp := reflect.New(v.Type())
+	if err := tmp.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+		return err
+	}
+	v.Set(tmp.Elem())
+	return nil
+}
+
+// 优先使用 encoding.TextMarshaler 格式化
+func rGetText(v reflect.Value) (s string, ok bool) {
+	if !v.Type().Implements(textMarshalerType) {
+		if !v.CanAddr() || !v.Addr().Type().Implements(textMarshalerType) {
+			return
+		}
+		v = v.Addr()
+	}
+
+	data, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	return string(data), err == nil
+}
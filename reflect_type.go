@@ -17,6 +17,8 @@ func rType(t reflect.Type, noExtend ...bool) string {
 		return rType(t.Elem(), noExtend...)
 	case reflect.Slice:
 		return rType(t.Elem(), noExtend...) + "s"
+	case reflect.Map:
+		return rType(t.Key(), noExtend...) + "=" + rType(t.Elem(), noExtend...)
 	default:
 		s := t.String()
 		for i := len(s) - 1; i >= 0 && s[i] != '/'; i-- {
@@ -28,10 +30,18 @@ func rType(t reflect.Type, noExtend ...bool) string {
 	}
 }
 
+// 去掉指针和切片后的元素类型
+func baseType(t reflect.Type) reflect.Type {
+	for !isKnown(t) && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	return t
+}
+
 // 判断类型是否基础类型: int*, uint*, float*, string, bool
 func isBasic(t reflect.Type) bool { return isBasicKind(t.Kind()) }
 
-func isKnown(t reflect.Type) bool { return HasExtend(t) || isBasic(t) }
+func isKnown(t reflect.Type) bool { return HasExtend(t) || isBasic(t) || isText(t) }
 
 func isAllow(in reflect.Type) bool {
 	var checkTypeInternal func(t reflect.Type, p, s bool) bool
@@ -44,6 +54,9 @@ func isAllow(in reflect.Type) bool {
 			return p && checkTypeInternal(t.Elem(), false, s)
 		case reflect.Slice:
 			return (s && checkTypeInternal(t.Elem(), true, false))
+		case reflect.Map:
+			// map[K]V, map[K][]V
+			return p && s && isKnown(t.Key()) && checkTypeInternal(t.Elem(), false, true)
 		default:
 			return false
 		}
@@ -3,7 +3,9 @@ package flags
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 func rVal(src any, indirect ...bool) reflect.Value {
@@ -26,6 +28,12 @@ func rGets(v reflect.Value) (out []string) {
 		return newSlice(te.Get(v))
 	}
 
+	if isText(v.Type()) {
+		if s, ok := rGetText(v); ok {
+			return newSlice(s)
+		}
+	}
+
 	switch kind := v.Kind(); kind {
 	case reflect.Pointer:
 		out = rGets(v.Elem())
@@ -33,6 +41,15 @@ func rGets(v reflect.Value) (out []string) {
 		for i := 0; i < v.Len(); i++ {
 			out = append(out, rGets(v.Index(i))...)
 		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			ks := strings.Join(rGets(k), "")
+			for _, s := range rGets(v.MapIndex(k)) {
+				out = append(out, ks+"="+s)
+			}
+		}
 	case reflect.String:
 		out = newSlice(v.String())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -56,6 +73,10 @@ func rSets(v reflect.Value, s string, reset ...bool) (err error) {
 		return te.Set(v, s)
 	}
 
+	if isText(v.Type()) {
+		return rSetText(v, s)
+	}
+
 	switch kind := v.Kind(); kind {
 	case reflect.String:
 		v.SetString(s)
@@ -71,6 +92,8 @@ func rSets(v reflect.Value, s string, reset ...bool) (err error) {
 		err = rSetPtr(v, s, len(reset) > 0 && reset[0])
 	case reflect.Slice:
 		err = rSetSs(v, s, len(reset) > 0 && reset[0])
+	case reflect.Map:
+		err = rSetMap(v, s, len(reset) > 0 && reset[0])
 	default:
 		err = fmt.Errorf("unknown kind: %s", kind.String())
 	}
@@ -104,6 +127,43 @@ func rSetSs(v reflect.Value, s string, reset bool) (err error) {
 	return
 }
 
+// 按 key=value 写入 map，值为切片时同一个 key 的值会累加
+func rSetMap(v reflect.Value, s string, reset bool) (err error) {
+	if !v.IsValid() || v.Kind() != reflect.Map {
+		return invalid("rSetMap")
+	}
+
+	ks, vs, found := strings.Cut(s, "=")
+	if !found {
+		return fmt.Errorf("%q must be formatted as key=value", s)
+	}
+
+	if v.IsNil() || reset {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+
+	key := reflect.New(v.Type().Key()).Elem()
+	if err = rSets(key, ks); err != nil {
+		return
+	}
+
+	el := reflect.New(v.Type().Elem()).Elem()
+	if el.Kind() == reflect.Slice {
+		if old := v.MapIndex(key); old.IsValid() {
+			el.Set(old)
+		}
+		err = rSetSs(el, vs, false)
+	} else {
+		err = rSets(el, vs)
+	}
+	if err != nil {
+		return
+	}
+
+	v.SetMapIndex(key, el)
+	return
+}
+
 func rSetPtr(v reflect.Value, s string, reset bool) (err error) {
 	if !v.IsValid() || v.Kind() != reflect.Pointer {
 		return invalid("rSetPtr")
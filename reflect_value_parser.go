@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -13,9 +14,18 @@ func init() {
 	Extend(rParseTime, rFormatTime)
 	Extend(rParseIp, rFormatIp)
 	Extend(rParseDuration, rFormatDuration)
+	Extend(rParseURL, rFormatURL)
+	Extend(rParseAddr, rFormatAddr)
+	Extend(rParsePrefix, rFormatPrefix)
+	Extend(rParseAddrPort, rFormatAddrPort)
+	Extend(rParseLocation, rFormatLocation)
 }
 
 func rParseTime(s string) (t time.Time, err error) {
+	if t, ok, e := rParseRelativeTime(s); ok || e != nil {
+		return t, e
+	}
+
 	if s != "" {
 		var allowTimeLayouts = []string{
 			time.DateTime, "2006-01-02 15:04", time.DateOnly, "01-02",
@@ -36,6 +46,25 @@ func rParseTime(s string) (t time.Time, err error) {
 	return
 }
 
+// 相对当前的时间: now, +1h, -24h, -1d12h
+func rParseRelativeTime(s string) (t time.Time, ok bool, err error) {
+	switch {
+	case s == "now":
+		return time.Now(), true, nil
+	case len(s) > 1 && (s[0] == '+' || s[0] == '-'):
+		d, e := rParseDuration(s[1:])
+		if e != nil {
+			return
+		}
+		if s[0] == '-' {
+			d = -d
+		}
+		return time.Now().Add(d), true, nil
+	default:
+		return
+	}
+}
+
 func rFormatTime(in time.Time) (s string) {
 	if !in.IsZero() {
 		s = strings.TrimSuffix(strings.TrimSuffix(in.Format(time.DateTime), ":00"), " 00:00")
@@ -98,3 +127,79 @@ func rFormatIp(in net.IP) (s string) {
 	}
 	return
 }
+
+func rParseURL(s string) (u url.URL, err error) {
+	if s != "" {
+		r, e := url.Parse(s)
+		switch {
+		case e != nil:
+			err = e
+		case r.Scheme == "":
+			err = fmt.Errorf("url scheme is required: %s", s)
+		case r.Host == "" && r.Opaque == "" && r.Scheme != "file":
+			err = fmt.Errorf("url host is required: %s", s)
+		default:
+			u = *r
+		}
+	}
+	return
+}
+
+func rFormatURL(in url.URL) string { return in.String() }
+
+func rParseAddr(s string) (r netip.Addr, err error) {
+	if s != "" {
+		r, err = netip.ParseAddr(s)
+	}
+	return
+}
+
+func rFormatAddr(in netip.Addr) (s string) {
+	if in.IsValid() {
+		s = in.String()
+	}
+	return
+}
+
+func rParsePrefix(s string) (r netip.Prefix, err error) {
+	if s != "" {
+		r, err = netip.ParsePrefix(s)
+	}
+	return
+}
+
+func rFormatPrefix(in netip.Prefix) (s string) {
+	if in.IsValid() {
+		s = in.String()
+	}
+	return
+}
+
+func rParseAddrPort(s string) (r netip.AddrPort, err error) {
+	if s != "" {
+		r, err = netip.ParseAddrPort(s)
+	}
+	return
+}
+
+func rFormatAddrPort(in netip.AddrPort) (s string) {
+	if in.IsValid() {
+		s = in.String()
+	}
+	return
+}
+
+// 时区: Asia/Shanghai, UTC, Local
+func rParseLocation(s string) (r *time.Location, err error) {
+	if s != "" {
+		r, err = time.LoadLocation(s)
+	}
+	return
+}
+
+func rFormatLocation(in *time.Location) (s string) {
+	if in != nil {
+		s = in.String()
+	}
+	return
+}
@@ -2,43 +2,147 @@ package flags
 
 import (
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 func newValue(v reflect.Value, t reflect.Type) *value {
 	vs := rGets(v)
-	return &value{v: v, typ: t, defVal: vs, args: vs}
+	val := &value{v: v, typ: t, args: vs}
+	val.setDefVal(vs)
+	return val
 }
 
 type value struct {
+	mu      sync.Mutex // 保护下面的状态，解析之后可以在多个 goroutine 中读取和热更新
 	v       reflect.Value
 	typ     reflect.Type
 	display string
 	changed bool
 	defVal  []string
+	defStr  string // 缓存 String() 的结果，默认值改变时重新生成
 	args    []string
+	rules   []rule
+	count   bool
+	negated bool
+
+	omitempty bool   // 低优先级来源中的零值不覆盖已有的值
+	envFirst  bool   // 环境变量的优先级高于命令行参数
+	secret    bool   // 敏感信息，输出时脱敏
+	summary   bool   // 包含在 SummaryBanner 中
+	source    Source // 当前值的来源
+	sourceKey string // 来源的位置，如环境变量名、配置文件路径
+	envKey    string // 读取到的环境变量名和值
+	envVal    string
+
+	base       []string // 第一次被配置文件覆盖之前的值和来源，配置文件中删除对应的项时恢复
+	baseSource Source
+	baseKey    string
+
+	envBase       []string // 读取环境变量之前的值和来源，加上前缀后重新读取环境变量时恢复
+	envBaseSource Source
+	envBaseKey    string
+
+	onSet []func(old, new string) // 值改变时的回调
+	flags *FlagSet                // 绑定到的参数集合，解析密钥引用时使用它的 context
+
+	parse  func(string) (string, error) // 写入前转换输入的值，如按指定的 layout 解析时间
+	format func(string) string          // 显示时转换当前的值
 }
 
-func (v *value) String() string {
-	if len(v.defVal) > 0 {
-		if v.IsSlice() {
-			return "[" + strings.Join(v.defVal, ",") + "]"
-		} else {
-			return v.defVal[0]
+// 设置输入输出的转换函数，并按照新的格式重新生成默认值
+func (v *value) setHooks(parse func(string) (string, error), format func(string) string) {
+	v.parse, v.format = parse, format
+	v.args = v.gets(v.v)
+	v.setDefVal(v.args)
+}
+
+// 记录默认值并生成 String() 的结果，复制一份避免 Set 时通过 args 修改默认值
+func (v *value) setDefVal(vs []string) {
+	v.defVal = append([]string(nil), vs...)
+
+	switch {
+	case len(vs) == 0:
+		v.defStr = ""
+	case v.IsSlice():
+		v.defStr = "[" + strings.Join(vs, ",") + "]"
+	default:
+		v.defStr = vs[0]
+	}
+}
+
+func (v *value) rset(target reflect.Value, s string, reset bool) (err error) {
+	if v.parse != nil {
+		if s, err = v.parse(s); err != nil {
+			return
+		}
+	}
+	return rSets(target, s, reset)
+}
+
+func (v *value) gets(target reflect.Value) []string {
+	out := rGets(target)
+	if v.format != nil {
+		for i, s := range out {
+			out[i] = v.format(s)
 		}
 	}
-	return ""
+	return out
+}
+
+func (v *value) String() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.defStr
 }
 
 func (v *value) Type() string {
 	if v.display != "" {
 		return "<" + v.display + ">"
 	}
+	if v.count {
+		return "count"
+	}
 	return rType(v.typ)
 }
 
 func (v *value) Set(s string) (err error) {
-	if err = rSets(v.v, s, !v.changed); err != nil {
+	v.mu.Lock()
+	var old string
+	if len(v.onSet) > 0 {
+		old = strings.Join(v.gets(v.v), ",")
+	}
+	err = v.set(s)
+	v.mu.Unlock()
+
+	// 回调在锁外执行，回调中可以读取参数的值
+	if len(v.onSet) > 0 {
+		v.fireSet(old)
+	}
+	return
+}
+
+func (v *value) set(s string) (err error) {
+	if v.count && s == "+1" {
+		var n int64
+		if rv := reflect.Indirect(v.v); rv.IsValid() && isIntKind(rv.Kind()) {
+			n = rv.Int()
+		}
+		s = strconv.FormatInt(n+1, 10)
+	}
+
+	// 密钥引用解析为实际的值写入，args 中保留引用
+	in := s
+	if s, err = v.resolve(s); err != nil {
+		return
+	}
+
+	if err = validateRules(v.typ, s, v.rules, v.parse); err != nil {
+		return
+	}
+
+	if err = v.rset(v.v, s, !v.changed); err != nil {
 		return
 	}
 
@@ -46,24 +150,107 @@ func (v *value) Set(s string) (err error) {
 		v.args = v.args[:0]
 	}
 
-	v.args = append(v.args, s)
+	v.args = append(v.args, in)
 	v.changed = true
+	v.negated = false
+	v.setSource(SourceFlag, "")
 	return
 }
 
 func (v *value) SetDefault(args ...string) (err error) {
+	return v.setDefault(SourceDefault, "", args...)
+}
+
+// 作为默认值写入并记录来源
+func (v *value) setDefault(src Source, key string, args ...string) (err error) {
+	if src == SourceConfig {
+		v.mu.Lock()
+		v.saveBase()
+		v.mu.Unlock()
+	}
+
 	for _, arg := range args {
 		if err = v.Set(arg); err != nil {
 			return
 		}
 	}
 
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	v.changed = false
-	v.defVal = v.args
+	v.setDefVal(v.args)
+	v.setSource(src, key)
 	return
 }
 
-func (v *value) Args() []string { return v.args }
+func (v *value) Args() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]string(nil), v.args...)
+}
+
+// 从结构体字段同步之后的当前值
+func (v *value) current() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.sync()
+	return append([]string(nil), v.args...)
+}
+
+// 解析密钥引用，解析过的参数标记为敏感信息
+func (v *value) resolve(s string) (string, error) {
+	out, resolved, err := resolveSecret(contextOf(v.flags), s)
+	if resolved {
+		v.secret = true
+	}
+	return out, err
+}
+
+func (v *value) setSource(src Source, key string) { v.source, v.sourceKey = src, key }
+
+// 复制一份独立的状态，仍然写入同一个字段，不复制值改变时的回调
+func (v *value) clone() *value {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return &value{
+		v: v.v, typ: v.typ, display: v.display, changed: v.changed,
+		defVal: append([]string(nil), v.defVal...), defStr: v.defStr, args: append([]string(nil), v.args...),
+		rules: v.rules, count: v.count, negated: v.negated,
+		omitempty: v.omitempty, envFirst: v.envFirst, secret: v.secret, summary: v.summary,
+		source: v.source, sourceKey: v.sourceKey, envKey: v.envKey, envVal: v.envVal,
+		base: v.base, baseSource: v.baseSource, baseKey: v.baseKey,
+		envBase: v.envBase, envBaseSource: v.envBaseSource, envBaseKey: v.envBaseKey,
+		flags: v.flags, parse: v.parse, format: v.format,
+	}
+}
+
+func (v *value) fireSet(old string) {
+	v.mu.Lock()
+	cur := strings.Join(v.gets(v.v), ",")
+	v.mu.Unlock()
+
+	if cur != old {
+		for _, fn := range v.onSet {
+			fn(old, cur)
+		}
+	}
+}
+
+// 从结构体字段重新读取当前值，程序中直接修改结构体后调用
+func (v *value) sync() {
+	live := v.gets(v.v)
+
+	tmp := reflect.New(v.typ).Elem()
+	for i, arg := range v.args {
+		if v.rset(tmp, arg, i == 0) != nil {
+			break
+		}
+	}
+
+	if strings.Join(v.gets(tmp), "\x00") != strings.Join(live, "\x00") {
+		v.args = live
+	}
+}
 
 func (v *value) DirectType() reflect.Type {
 	if v.typ.Kind() == reflect.Pointer {
@@ -72,5 +259,12 @@ func (v *value) DirectType() reflect.Type {
 	return v.typ
 }
 
-func (v *value) IsBool() bool  { return v.DirectType().Kind() == reflect.Bool }
-func (v *value) IsSlice() bool { return v.DirectType().Kind() == reflect.Slice }
+func (v *value) IsBool() bool { return v.DirectType().Kind() == reflect.Bool }
+
+// 切片和 map 可以多次设置，值会累加，net.IP, json.RawMessage 等扩展类型作为单个值
+func (v *value) IsSlice() bool {
+	if t := v.DirectType(); !HasExtend(t) {
+		return t.Kind() == reflect.Slice || t.Kind() == reflect.Map
+	}
+	return false
+}
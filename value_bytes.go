@@ -0,0 +1,67 @@
+package flags
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var typeBytes = reflect.TypeOf([]byte(nil))
+
+func init() {
+	Extend(func(s string) ([]byte, error) { return []byte(s), nil }, func(b []byte) string { return string(b) })
+}
+
+// 绑定时检查 encoding 标签，不支持的编码返回错误，而不是按 raw 处理
+func checkEncoding(field *FlagField) error {
+	switch enc := getTag(field.Field.Tag, _TAG_ENCODING); strings.ToLower(enc) {
+	case "", "raw", "hex", "base64":
+		return nil
+	default:
+		return fmt.Errorf("flag %q: unsupported encoding %q, want hex, base64 or raw", field.Name, enc)
+	}
+}
+
+// 标签 `encoding:"hex|base64|raw"`，[]byte 字段按指定的编码解析和显示，默认 raw
+func fieldEncoding(field *FlagField) {
+	enc := strings.ToLower(getTag(field.Field.Tag, _TAG_ENCODING))
+	if enc == "" || enc == "raw" || baseType(field.Value.typ) != typeBytes {
+		return
+	}
+
+	var (
+		decode func(string) ([]byte, error)
+		encode func([]byte) string
+	)
+
+	switch enc {
+	case "hex":
+		decode = hex.DecodeString
+		encode = hex.EncodeToString
+	case "base64":
+		decode = func(s string) ([]byte, error) {
+			if strings.HasSuffix(s, "=") {
+				return base64.StdEncoding.DecodeString(s)
+			}
+			return base64.RawStdEncoding.DecodeString(s)
+		}
+		encode = base64.StdEncoding.EncodeToString
+	default:
+		return
+	}
+
+	parse := func(s string) (string, error) {
+		b, err := decode(strings.TrimSpace(s))
+		if err != nil {
+			return s, fmt.Errorf("invalid %s: %w", enc, err)
+		}
+		return string(b), nil
+	}
+
+	format := func(s string) string { return encode([]byte(s)) }
+
+	field.Value.setHooks(parse, format)
+	field.Value.display = enc
+}
@@ -0,0 +1,99 @@
+package flags
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 字节大小，支持 10KB, 5MiB, 1.5G 等写法
+//
+// KB/MB/GB/TB 按 1000 进制，KiB/MiB/GiB/TiB 以及 K/M/G/T 按 1024 进制
+type ByteSize uint64
+
+func (b ByteSize) String() string { return rFormatByteSize(b) }
+
+func init() {
+	Extend(rParseByteSize, rFormatByteSize)
+}
+
+var byteUnits = []struct {
+	name string
+	size uint64
+}{
+	{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+func rParseByteSize(s string) (b ByteSize, err error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return
+	}
+
+	num, size := s, uint64(1)
+	for _, u := range byteUnits {
+		if len(s) > len(u.name) && strings.EqualFold(s[len(s)-len(u.name):], u.name) {
+			num, size = strings.TrimSpace(s[:len(s)-len(u.name)]), u.size
+			break
+		}
+	}
+
+	// 最大按 int64 限制，int64 字段也可以使用字节大小
+	if n, e := strconv.ParseUint(num, 10, 64); e == nil {
+		if n > math.MaxInt64/size {
+			return 0, fmt.Errorf("byte size out of range: %s", s)
+		}
+		return ByteSize(n * size), nil
+	}
+
+	f, e := strconv.ParseFloat(num, 64)
+	if e != nil || f < 0 || math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid byte size: %s", s)
+	}
+	if f *= float64(size); f >= math.MaxInt64 {
+		return 0, fmt.Errorf("byte size out of range: %s", s)
+	}
+	return ByteSize(f), nil
+}
+
+// 按能整除的最大单位显示，优先使用 1024 进制
+func rFormatByteSize(b ByteSize) string {
+	if b == 0 {
+		return ""
+	}
+	for _, u := range byteUnits {
+		if u.size > 1 && len(u.name) > 1 && uint64(b)%u.size == 0 {
+			return strconv.FormatUint(uint64(b)/u.size, 10) + u.name
+		}
+	}
+	return strconv.FormatUint(uint64(b), 10) + "B"
+}
+
+// 标签 `size:"true"`，int64/uint64 等整数字段按字节大小解析和显示
+func fieldByteSize(field *FlagField) {
+	if !tagBool(field.Field.Tag, _TAG_SIZE) {
+		return
+	}
+
+	if t := baseType(field.Value.typ); !isIntKind(t.Kind()) && !isUintKind(t.Kind()) {
+		return
+	}
+
+	parse := func(s string) (string, error) {
+		b, err := rParseByteSize(s)
+		return strconv.FormatUint(uint64(b), 10), err
+	}
+
+	format := func(s string) string {
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return rFormatByteSize(ByteSize(n))
+		}
+		return s
+	}
+
+	field.Value.setHooks(parse, format)
+	field.Value.display = "size"
+}
@@ -1,11 +1,13 @@
 package flags
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 )
 
 var _ = isConfigFile
@@ -15,10 +17,12 @@ var typeConfigFile = reflect.TypeOf(ConfigFile(""))
 func isConfigFile(t reflect.Type) bool { return t == typeConfigFile }
 
 func BindFile(structPtr any, name, shorthand, defVal, usage string, flags ...*FlagSet) {
-	v := &configFileValue{structPtr: structPtr, path: defVal}
-	for _, set := range flags {
-		set.VarP(v, shorthand, defVal, usage)
-	}
+	set := flagSet(flags)
+	v := &configFileValue{structPtr: structPtr, path: defVal, set: set}
+	meta := metaOf(set)
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	set.VarP(v, name, shorthand, usage)
 }
 
 type ConfigFile string
@@ -26,28 +30,139 @@ type ConfigFile string
 type configFileValue struct {
 	path      string
 	structPtr any
+	set       *FlagSet
 }
 
 func (b *configFileValue) String() string { return b.path }
 func (b *configFileValue) Type() string   { return "configfile" }
 func (b *configFileValue) Set(s string) (err error) {
 	if b.path = s; b.path != "" {
+		defer metaOf(b.set).stats.load(SourceConfig, time.Now())
 		ct, path := getCotentType(s)
-		switch ct {
-		case "json":
-			_, err = readBytes(path, UnmarshalJSON(b.structPtr))
-		case "yaml":
-			_, err = readBytes(path, UnmarshalYAML(b.structPtr))
-		case "toml":
-			_, err = readBytes(path, UnmarshalToml(b.structPtr))
-		case "ini":
-			_, err = readBytes(path, UnmarshalIni(b.structPtr))
-		default:
-			err = fmt.Errorf("unsupported config file: %s", s)
-		}
-		if os.IsNotExist(err) {
+		before := configSnapshot(b.set)
+		if _, err = readBytes(contextOf(b.set), path, func(data []byte) error { return LoadConfig(b.structPtr, ct, data) }); os.IsNotExist(err) {
 			err = nil
 		}
+		markConfigSource(b.set, before, path)
+	}
+	return
+}
+
+// 加载配置文件前各参数的值
+func configSnapshot(set *FlagSet) map[*value]string {
+	before := map[*value]string{}
+	for _, field := range metaOf(set).fields {
+		v := field.Value
+		v.mu.Lock()
+		before[v] = strings.Join(v.gets(v.v), "\x00")
+		v.mu.Unlock()
+	}
+	return before
+}
+
+// 配置文件直接写入结构体，加载后把值有变化的参数记录为来自配置文件
+func markConfigSource(set *FlagSet, before map[*value]string, path string) {
+	for v, old := range before {
+		v.mu.Lock()
+		if cur := v.gets(v.v); strings.Join(cur, "\x00") != old {
+			v.saveBase()
+			resolveConfigSecrets(contextOf(set), v, cur)
+			v.args = cur
+			v.setSource(SourceConfig, path)
+		}
+		v.mu.Unlock()
+	}
+}
+
+// 记录被配置文件覆盖之前的值和来源，需要持有 v.mu
+func (v *value) saveBase() {
+	if v.source != SourceConfig {
+		v.base = append([]string(nil), v.args...)
+		v.baseSource, v.baseKey = v.source, v.sourceKey
+	}
+}
+
+// 恢复被配置文件覆盖之前的值和来源
+func (v *value) restoreBase() error {
+	v.mu.Lock()
+	base, src, key := v.base, v.baseSource, v.baseKey
+	v.mu.Unlock()
+	return v.restore(base, src, key)
+}
+
+// 作为默认值写入 base，base 为空时恢复为零值
+func (v *value) restore(base []string, src Source, key string) error {
+	v.mu.Lock()
+	if len(base) == 0 {
+		defer v.mu.Unlock()
+		target := reflect.Indirect(v.v)
+		target.Set(reflect.Zero(target.Type()))
+		v.args, v.changed = nil, false
+		v.setDefVal(nil)
+		v.setSource(src, key)
+		return nil
+	}
+	v.mu.Unlock()
+	return v.setDefault(src, key, base...)
+}
+
+// 配置文件中的密钥引用解析后重新写入结构体，出错时保留原来的值并输出警告
+func resolveConfigSecrets(ctx context.Context, v *value, cur []string) {
+	out := make([]string, len(cur))
+	var resolved bool
+	for i, s := range cur {
+		ok, err := false, error(nil)
+		if out[i], ok, err = resolveSecret(ctx, s); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] %v\n", err)
+			return
+		}
+		resolved = resolved || ok
+	}
+
+	if resolved {
+		v.secret = true
+		for i, s := range out {
+			if err := v.rset(v.v, s, i == 0); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] %v\n", err)
+				return
+			}
+		}
+	}
+}
+
+// 从配置内容加载到结构体，contentType 支持 json, yaml, toml, ini
+//
+// 标记了 `merge:"omitempty"` 的字段，配置中的零值不会覆盖已有的值
+func LoadConfig(structPtr any, contentType string, data []byte) (err error) {
+	fields, _ := ParseStruct(structPtr)
+	saved := map[*FlagField]reflect.Value{}
+	for _, field := range fields {
+		if field.Value.omitempty && !field.Referer.IsZero() {
+			old := reflect.New(field.Referer.Type()).Elem()
+			old.Set(field.Referer)
+			saved[field] = old
+		}
+	}
+
+	defer func() {
+		for field, old := range saved {
+			if field.Referer.IsZero() {
+				field.Referer.Set(old)
+			}
+		}
+	}()
+
+	switch contentType {
+	case "json":
+		err = UnmarshalJSON(structPtr)(data)
+	case "yaml":
+		err = UnmarshalYAML(structPtr)(data)
+	case "toml":
+		err = UnmarshalToml(structPtr)(data)
+	case "ini":
+		err = UnmarshalIni(structPtr)(data)
+	default:
+		err = fmt.Errorf("unsupported config type: %s", contentType)
 	}
 	return
 }
@@ -58,14 +173,16 @@ func getCotentType(s string) (ct, path string) {
 		if ct, path, ok = strings.Cut(s, ":"); ok {
 			switch ct {
 			case "json", "yaml", "toml", "ini":
+				return
 			default:
 				ok = false
 			}
-			return
 		}
 
 		if !ok {
-			switch path, ct = s, strings.TrimPrefix(filepath.Ext(s), "."); ct {
+			// 远程地址的查询参数不属于扩展名，如 git+https://host/repo.git//app.yaml?ref=v1
+			ext, _, _ := strings.Cut(filepath.Ext(s), "?")
+			switch path, ct = s, strings.TrimPrefix(ext, "."); ct {
 			case "jsonc":
 				ct = "json"
 			case "yml":
@@ -81,9 +198,23 @@ func getCotentType(s string) (ct, path string) {
 
 type drFunc = func(data []byte) (err error)
 
-func readBytes(filename string, read drFunc) (data []byte, err error) {
+func readBytes(ctx context.Context, filename string, read drFunc) (data []byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	if filename != "" {
-		if data, err = os.ReadFile(filename); err == nil {
+		switch {
+		case strings.HasPrefix(filename, "scp://"), strings.HasPrefix(filename, "sftp://"):
+			data, err = fetchSSH(ctx, filename)
+		case strings.HasPrefix(filename, "git+"):
+			data, err = fetchGit(ctx, filename)
+		case strings.HasPrefix(filename, "s3://"), strings.HasPrefix(filename, "gs://"), strings.HasPrefix(filename, "azblob://"):
+			data, err = fetchBlob(ctx, filename)
+		default:
+			data, err = os.ReadFile(filename)
+		}
+		if err == nil {
 			err = read(data)
 		}
 	}
@@ -0,0 +1,53 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// 从对象存储读取配置文件，调用各云厂商的命令行工具，使用工具自身的凭证链(环境变量、配置文件、实例角色等)
+//
+// 需要轮询时使用 WatchConfig，每次都重新下载对象比较内容
+//
+//	s3://bucket/path/app.yaml                 aws s3 cp
+//	gs://bucket/path/app.yaml                 gcloud storage cat
+//	azblob://account/container/path/app.yaml  az storage blob download
+func fetchBlob(ctx context.Context, rawURL string) ([]byte, error) {
+	name, args, err := blobCommand(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return fetchCommand(ctx, rawURL, name, args...)
+}
+
+func blobCommand(rawURL string) (name string, args []string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return "", nil, fmt.Errorf("object url must be %s://bucket/key; got %s", u.Scheme, rawURL)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		return "aws", []string{"s3", "cp", "--only-show-errors", "s3://" + u.Host + "/" + key, "-"}, nil
+	case "gs":
+		return "gcloud", []string{"storage", "cat", "gs://" + u.Host + "/" + key}, nil
+	case "azblob":
+		container, blob, _ := strings.Cut(key, "/")
+		if blob == "" {
+			return "", nil, fmt.Errorf("azure blob url must be azblob://account/container/blob; got %s", rawURL)
+		}
+		return "az", []string{
+			"storage", "blob", "download", "--auth-mode", "login", "--no-progress", "--output", "none",
+			"--account-name", u.Host, "--container-name", container, "--name", blob, "--file", "/dev/stdout",
+		}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported object storage: %s", u.Scheme)
+	}
+}
@@ -0,0 +1,101 @@
+package flags
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 从 git 仓库读取配置文件: git+https://host/repo.git//path/app.yaml?ref=v1.2.0
+//
+// 调用系统的 git 命令浅克隆(--depth 1)指定的 ref，仓库缓存在用户缓存目录下。
+// 指定了 ref(标签或者提交)时优先使用缓存，不指定时每次都重新获取默认分支
+func fetchGit(ctx context.Context, rawURL string) ([]byte, error) {
+	repo, path, ref, err := parseGitURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	if err = checkGitRef(ctx, rawURL, ref); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(repo + "\x00" + ref))
+	dir := filepath.Join(cache, "cnk3x-flags", "git", hex.EncodeToString(sum[:8]))
+
+	const pinned = "refs/flags/pinned"
+	git := func(args ...string) ([]byte, error) {
+		return fetchCommand(ctx, rawURL, "git", append([]string{"-C", dir}, args...)...)
+	}
+
+	if ref != "" {
+		if data, err := git("show", pinned+":"+path); err == nil {
+			return data, nil
+		}
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, "HEAD")); err != nil {
+		if err = os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		if _, err = git("init", "-q", "--bare"); err != nil {
+			return nil, err
+		}
+	}
+
+	src := ref
+	if src == "" {
+		src = "HEAD"
+	}
+	if _, err = git("fetch", "-q", "--depth", "1", "--force", "--", repo, src+":"+pinned); err != nil {
+		return nil, err
+	}
+	return git("show", pinned+":"+path)
+}
+
+// 拆分仓库地址、仓库中的文件路径和 ref
+func parseGitURL(rawURL string) (repo, path, ref string, err error) {
+	s := strings.TrimPrefix(rawURL, "git+")
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return
+	}
+	ref = u.Query().Get("ref")
+	// 以 `-` 开头的 ref 会被 git 当作选项，如 --upload-pack=... 会执行命令
+	if strings.HasPrefix(ref, "-") {
+		return "", "", "", fmt.Errorf("invalid git ref %q in %s", ref, rawURL)
+	}
+	u.RawQuery = ""
+	s = u.String()
+
+	scheme, rest, _ := strings.Cut(s, "://")
+	repoPath, path, found := strings.Cut(rest, "//")
+	if !found || path == "" || repoPath == "" {
+		return "", "", "", fmt.Errorf("git config url must be git+<repo>//<path>[?ref=<ref>]; got %s", rawURL)
+	}
+	return scheme + "://" + repoPath, path, ref, nil
+}
+
+var gitSHA = regexp.MustCompile(`^[0-9a-fA-F]{7,64}$`)
+
+// ref 必须是提交的哈希或者合法的引用名
+func checkGitRef(ctx context.Context, rawURL, ref string) error {
+	if ref == "" || gitSHA.MatchString(ref) {
+		return nil
+	}
+	if _, err := fetchCommand(ctx, rawURL, "git", "check-ref-format", "--allow-onelevel", ref); err != nil {
+		return fmt.Errorf("invalid git ref %q in %s", ref, rawURL)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package flags
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// 注册直接传入 JSON 内容的参数，如 `--config-json '{"port":9090}'`，和配置文件一样合并到结构体
+//
+// env 不为空时，绑定时读取该环境变量作为初始的配置，命令行参数(包括 name 参数)会覆盖其中的值，
+// 适合容器编排中通过一个环境变量传入整个配置的场景，应在 StructBind 之后调用
+func BindJSON(structPtr any, name, env, usage string, flags ...*FlagSet) {
+	set := flagSet(flags)
+	v := &inlineConfigValue{structPtr: structPtr, set: set, key: "--" + name}
+	set.Var(v, name, usage)
+	if env == "" {
+		return
+	}
+
+	set.Lookup(name).Usage += fmt.Sprintf(" (env: %s)", env)
+	if s := getenv(env); s != "" {
+		v.key = env
+		if err := v.Set(s); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARN] 环境变量参数[%s]的配置无效: %v\n", env, err)
+		}
+		v.raw, v.key = "", "--"+name
+	}
+}
+
+type inlineConfigValue struct {
+	raw       string
+	key       string // 记录到参数来源中的位置
+	structPtr any
+	set       *FlagSet
+}
+
+func (v *inlineConfigValue) String() string { return v.raw }
+func (v *inlineConfigValue) Type() string   { return "json" }
+func (v *inlineConfigValue) Set(s string) (err error) {
+	if v.raw = s; strings.TrimSpace(s) == "" {
+		return
+	}
+
+	defer metaOf(v.set).stats.load(SourceConfig, time.Now())
+	before := configSnapshot(v.set)
+	if err = LoadConfig(v.structPtr, "json", []byte(s)); err == nil {
+		markConfigSource(v.set, before, v.key)
+	}
+	return
+}
+
+// 从环境变量读取 base64 编码的 YAML 或 JSON 配置，如 `MYAPP_CONFIG_B64`，合并到结构体，优先级同配置文件
+//
+// 适合通过会破坏换行的系统(部分 CI、编排工具的模板)注入多行的配置，应在 StructBind 之后调用
+func BindConfigEnv(structPtr any, env string, flags ...*FlagSet) error {
+	s := getenv(env)
+	if s == "" {
+		return nil
+	}
+
+	data, err := decodeBase64(s)
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", env, err)
+	}
+
+	ct := "yaml"
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		ct = "json"
+	}
+
+	set := flagSet(flags)
+	defer metaOf(set).stats.load(SourceConfig, time.Now())
+	before := configSnapshot(set)
+	if err = LoadConfig(structPtr, ct, data); err != nil {
+		return fmt.Errorf("load %s: %w", env, err)
+	}
+	markConfigSource(set, before, env)
+	return nil
+}
+
+// 兼容标准和 URL 安全的编码，有无填充都可以，忽略其中的空白
+func decodeBase64(s string) (data []byte, err error) {
+	s = strings.Join(strings.Fields(s), "")
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err = enc.DecodeString(s); err == nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,73 @@
+package flags
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// 通过 ssh 读取远程配置文件: scp://[user@]host[:port]/path/to/app.yaml
+//
+// 调用系统的 ssh 命令，使用用户的 ssh-agent、密钥和 ~/.ssh/config，不会交互询问密码
+func fetchSSH(ctx context.Context, rawURL string) ([]byte, error) {
+	args, err := sshArgs(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return fetchCommand(ctx, rawURL, "ssh", args...)
+}
+
+// 执行命令读取远程配置，命令的标准输出为配置内容
+func fetchCommand(ctx context.Context, rawURL, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+
+	data, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("fetch %s: %w", rawURL, err)
+	}
+	return data, nil
+}
+
+func sshArgs(rawURL string) (args []string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+
+	if u.Hostname() == "" || u.Path == "" || u.Path == "/" {
+		return nil, fmt.Errorf("ssh config url must be scp://[user@]host[:port]/path; got %s", rawURL)
+	}
+
+	// 以 `-` 开头的主机名或者用户名会被 ssh 当作选项，如 -oProxyCommand=... 会执行命令
+	if strings.HasPrefix(u.Hostname(), "-") || (u.User != nil && strings.HasPrefix(u.User.Username(), "-")) {
+		return nil, fmt.Errorf("ssh config url has invalid host or user: %s", rawURL)
+	}
+
+	args = []string{"-o", "BatchMode=yes"}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+
+	target := u.Hostname()
+	if u.User != nil && u.User.Username() != "" {
+		target = u.User.Username() + "@" + target
+	}
+
+	// scp://host/~/app.yaml 读取用户目录下的文件
+	path := u.Path
+	if strings.HasPrefix(path, "/~/") {
+		path = path[3:]
+	}
+
+	return append(args, "--", target, "cat", "--", shellQuote(path)), nil
+}
+
+func shellQuote(s string) string { return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'" }
@@ -0,0 +1,195 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 每隔 interval 重新读取 BindFile 绑定的配置文件，内容变化时把变化的值写入参数，ctx 取消后停止，同 WatchConfigPoll
+func WatchConfig(ctx context.Context, name string, interval time.Duration, onError func(error), flags ...*FlagSet) error {
+	return WatchConfigPoll(ctx, name, Poll{Interval: interval}, onError, flags...)
+}
+
+// 按 poll 的设置重新读取 BindFile 绑定的配置文件，内容变化时把变化的值写入参数，ctx 取消后停止
+//
+// 每次都按路径重新读取文件内容比较，而不是检查文件的修改时间，
+// 所以 Kubernetes 挂载的 ConfigMap/Secret 通过替换 `..data` 软链接更新时也能正确识别，
+// 对象存储(s3://、gs://、azblob://)等远程的配置文件每次重新下载。
+// 变化的值通过 SetAll 全部校验通过后才会写入，来自命令行、环境变量的参数不会被覆盖，
+// 配置文件中删除的项恢复为默认值或者环境变量的值，写入后调用 OnReload 注册的函数。
+// 轮询时读取、写入或者 OnReload 出错时调用 onError，onError 可以为 nil，Refresh 时返回错误
+func WatchConfigPoll(ctx context.Context, name string, poll Poll, onError func(error), flags ...*FlagSet) error {
+	if err := poll.check(); err != nil {
+		return err
+	}
+
+	set := flagSet(flags)
+	f := set.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("flag %q not defined", name)
+	}
+	cv, ok := f.Value.(*configFileValue)
+	if !ok {
+		return fmt.Errorf("flag %q is not a config file flag", name)
+	}
+
+	last, err := snapshotConfig(ctx, cv)
+	if err != nil {
+		return err
+	}
+
+	refresh := addWatcher(ctx, set, func(ctx context.Context) error {
+		cur, err := snapshotConfig(ctx, cv)
+		if err != nil {
+			return err
+		}
+		changed, err := applyConfigChanges(set, last, cur)
+		if err != nil {
+			return err
+		}
+		last = cur
+		return reloaded(ctx, set, changed)
+	})
+
+	go poll.run(ctx, func() {
+		if err := refresh(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+	})
+	return nil
+}
+
+// 取出配置文件中出现的参数的值
+//
+// 配置文件分别加载到一份零值的结构体和一份预先填入非零值的结构体上，两份结果相同的字段就是配置文件中出现的字段，
+// 这样不依赖各个格式的键名规则。无法预先填入值的类型(如 time.Time)以非零值作为出现的依据
+func snapshotConfig(ctx context.Context, cv *configFileValue) (values map[string]string, err error) {
+	v, err := structValue(cv.structPtr)
+	if err != nil {
+		return
+	}
+
+	ct, path := getCotentType(cv.path)
+	data, err := readBytes(ctx, path, func([]byte) error { return nil })
+	if err != nil {
+		return
+	}
+
+	zero, seeded := reflect.New(v.Type()), reflect.New(v.Type())
+	fields, err := ParseStruct(seeded)
+	if err != nil {
+		return
+	}
+	seedable := make([]bool, len(fields))
+	for i, field := range fields {
+		seedable[i] = seedValue(field.Referer)
+	}
+
+	if err = LoadConfig(zero.Interface(), ct, data); err != nil {
+		return
+	}
+	if err = LoadConfig(seeded.Interface(), ct, data); err != nil {
+		return
+	}
+
+	loaded, err := ParseStruct(zero)
+	if err != nil {
+		return
+	}
+	seededFields, err := ParseStruct(seeded)
+	if err != nil {
+		return
+	}
+
+	values = map[string]string{}
+	for i, field := range loaded {
+		present := !field.Referer.IsZero()
+		if seedable[i] {
+			present = reflect.DeepEqual(field.Referer.Interface(), seededFields[i].Referer.Interface())
+		}
+		if present {
+			values[field.Name] = strings.Join(field.Value.Args(), ",")
+		}
+	}
+	return
+}
+
+// 填入一个非零值，无法构造时返回 false
+func seedValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	case reflect.String:
+		v.SetString("\x00")
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+	default:
+		return false
+	}
+	return true
+}
+
+// 写入配置文件中变化的值，删除的项恢复为配置文件之前的值(默认值或者环境变量)，返回变化的参数
+//
+// 当前来源优先级高于配置文件的参数(环境变量、命令行、交互输入)不会被修改
+func applyConfigChanges(set *FlagSet, last, cur map[string]string) (changed map[string]string, err error) {
+	meta := metaOf(set)
+	changes := map[string]string{}
+	removed := map[string]*value{}
+	for name, field := range meta.fieldList() {
+		f := set.Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+
+		v := field.Value
+		v.mu.Lock()
+		src := v.source
+		v.mu.Unlock()
+		if outranksConfig(src) {
+			continue
+		}
+
+		s, ok := cur[name]
+		prev, had := last[name]
+		switch {
+		case ok && (!had || prev != s):
+			changes[name] = s
+		case !ok && had && src == SourceConfig:
+			removed[name] = v
+		}
+	}
+
+	if len(changes) > 0 {
+		if err = SetAll(set, changes, SourceConfig); err != nil {
+			return
+		}
+	}
+	for name, v := range removed {
+		if err = v.restoreBase(); err != nil {
+			return
+		}
+		changes[name] = strings.Join(v.current(), ",")
+	}
+	return changes, nil
+}
+
+// 来源的优先级是否高于配置文件
+func outranksConfig(src Source) bool {
+	switch src {
+	case SourceEnv, SourceParent, SourceFlag, SourcePrompt:
+		return true
+	}
+	return false
+}
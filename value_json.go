@@ -0,0 +1,50 @@
+package flags
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+func init() {
+	Extend(rParseRawJSON, rFormatRawJSON)
+	Extend(rParseJSONObject, rFormatJSONObject)
+}
+
+// JSON 字面量，如 `--extra '{"a":1}'`，解析时校验格式
+func rParseRawJSON(s string) (r json.RawMessage, err error) {
+	if s == "" {
+		return
+	}
+	if !json.Valid([]byte(s)) {
+		return nil, fmt.Errorf("invalid json: %s", s)
+	}
+	return json.RawMessage(s), nil
+}
+
+func rFormatRawJSON(in json.RawMessage) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, in) != nil {
+		return string(in)
+	}
+	return buf.String()
+}
+
+// JSON 对象，如 `--labels '{"app":"web"}'`
+func rParseJSONObject(s string) (m map[string]any, err error) {
+	if s == "" {
+		return
+	}
+	if err = json.Unmarshal([]byte(s), &m); err != nil {
+		return nil, fmt.Errorf("invalid json object: %w", err)
+	}
+	return
+}
+
+func rFormatJSONObject(in map[string]any) (s string) {
+	if len(in) > 0 {
+		data, _ := json.Marshal(in)
+		s = string(data)
+	}
+	return
+}
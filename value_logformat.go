@@ -0,0 +1,97 @@
+//go:build go1.21
+
+package flags
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// 日志格式: text, json, console
+type LogFormat string
+
+const (
+	LogFormatText    LogFormat = "text"
+	LogFormatJSON    LogFormat = "json"
+	LogFormatConsole LogFormat = "console"
+)
+
+func (f LogFormat) String() string { return string(f) }
+
+// 按格式创建 slog.Handler，格式为空时使用 text
+func (f LogFormat) Handler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+	switch f {
+	case LogFormatJSON:
+		return slog.NewJSONHandler(w, opts)
+	case LogFormatConsole:
+		return slog.NewTextHandler(w, consoleOptions(opts))
+	default:
+		return slog.NewTextHandler(w, opts)
+	}
+}
+
+// console 格式的时间只保留时分秒
+func consoleOptions(opts *slog.HandlerOptions) *slog.HandlerOptions {
+	o := slog.HandlerOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	replace := o.ReplaceAttr
+	o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+			a.Value = slog.StringValue(a.Value.Time().Format(time.TimeOnly))
+		}
+		if replace != nil {
+			a = replace(groups, a)
+		}
+		return a
+	}
+	return &o
+}
+
+// 日志级别和格式参数，嵌入到配置结构体中使用
+//
+//	type Config struct {
+//		flags.Logging
+//	}
+//
+//	slog.SetDefault(cfg.Logger(os.Stderr))
+type Logging struct {
+	Level  slog.Level `flag:"log-level" usage:"日志级别: debug, info, warn, error"`
+	Format LogFormat  `flag:"log-format" usage:"日志格式: text, json, console"`
+}
+
+func (l Logging) Handler(w io.Writer) slog.Handler {
+	return l.Format.Handler(w, &slog.HandlerOptions{Level: l.Level})
+}
+
+func (l Logging) Logger(w io.Writer) *slog.Logger { return slog.New(l.Handler(w)) }
+
+func init() {
+	Extend(rParseLogFormat, rFormatLogFormat)
+	Extend(rParseLevel, rFormatLevel)
+}
+
+func rParseLogFormat(s string) (f LogFormat, err error) {
+	switch f = LogFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case "", LogFormatText, LogFormatJSON, LogFormatConsole:
+	default:
+		err = fmt.Errorf("log format must be one of [text, json, console]; got %s", s)
+	}
+	return
+}
+
+func rFormatLogFormat(in LogFormat) string { return string(in) }
+
+func rParseLevel(s string) (l slog.Level, err error) {
+	if s != "" {
+		err = l.UnmarshalText([]byte(s))
+	}
+	return
+}
+
+func rFormatLevel(in slog.Level) string { return strings.ToLower(in.String()) }
@@ -0,0 +1,41 @@
+//go:build go1.21
+
+package flags
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestLogging(t *testing.T) {
+	var c struct {
+		Logging
+	}
+
+	set := pflag.NewFlagSet("logging", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if err := set.Parse([]string{"--log-level", "warn", "--log-format", "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != slog.LevelWarn || c.Format != LogFormatJSON {
+		t.Fatalf("got %+v", c)
+	}
+
+	var buf strings.Builder
+	logger := c.Logger(&buf)
+	logger.Info("skip")
+	logger.Warn("hello", "n", 1)
+	if s := buf.String(); strings.Contains(s, "skip") || !strings.Contains(s, `"msg":"hello"`) {
+		t.Errorf("log = %s", s)
+	}
+
+	for _, args := range [][]string{{"--log-level", "loud"}, {"--log-format", "xml"}} {
+		if err := set.Parse(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// 系统日志目标
+//
+//	syslog:                                 本机 syslog
+//	syslog://localhost:514?facility=local0  远程 syslog, 默认 udp
+//	syslog+tcp://10.0.0.1:514?tag=app       使用 tcp 发送
+//	journald:                               systemd-journald
+type LogTarget struct {
+	Scheme   string // syslog, journald
+	Network  string // udp, tcp, 为空时使用本机 syslog
+	Addr     string
+	Facility string // 默认 user
+	Tag      string // 默认程序名
+}
+
+func (t LogTarget) String() string { return rFormatLogTarget(t) }
+
+// 打开日志目标，写入的每一次调用作为一条日志
+func (t LogTarget) Open() (io.WriteCloser, error) {
+	tag := t.Tag
+	if tag == "" {
+		tag = name()
+	}
+
+	switch t.Scheme {
+	case "syslog":
+		return openSyslog(t.Network, t.Addr, syslogFacilities[t.facility()], tag)
+	case "journald":
+		return openJournald(tag)
+	default:
+		return nil, fmt.Errorf("unknown log target: %s", t.Scheme)
+	}
+}
+
+func (t LogTarget) facility() string {
+	if t.Facility == "" {
+		return "user"
+	}
+	return t.Facility
+}
+
+// RFC 5424 facility
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+func init() {
+	Extend(rParseLogTarget, rFormatLogTarget)
+}
+
+func rParseLogTarget(s string) (t LogTarget, err error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return
+	}
+
+	scheme, network, _ := strings.Cut(strings.ToLower(u.Scheme), "+")
+	switch scheme {
+	case "syslog":
+		t.Scheme = scheme
+		if u.Host != "" {
+			if network == "" {
+				network = "udp"
+			}
+			if network != "udp" && network != "tcp" {
+				return t, fmt.Errorf("unsupported syslog network: %s", network)
+			}
+			t.Network, t.Addr = network, u.Host
+			if u.Port() == "" {
+				t.Addr += ":514"
+			}
+		}
+	case "journald":
+		t.Scheme = scheme
+	default:
+		return t, fmt.Errorf("log target must be syslog:// or journald: ; got %s", s)
+	}
+
+	q := u.Query()
+	if t.Facility = strings.ToLower(q.Get("facility")); t.Facility != "" {
+		if _, ok := syslogFacilities[t.Facility]; !ok {
+			return t, fmt.Errorf("unknown syslog facility: %s", t.Facility)
+		}
+	}
+	t.Tag = q.Get("tag")
+	return
+}
+
+func rFormatLogTarget(in LogTarget) (s string) {
+	if in.Scheme == "" {
+		return
+	}
+
+	u := url.URL{Scheme: in.Scheme, Host: in.Addr}
+	if in.Network != "" && in.Network != "udp" {
+		u.Scheme += "+" + in.Network
+	}
+
+	q := url.Values{}
+	if in.Facility != "" {
+		q.Set("facility", in.Facility)
+	}
+	if in.Tag != "" {
+		q.Set("tag", in.Tag)
+	}
+	u.RawQuery = q.Encode()
+
+	if s = u.String(); in.Addr == "" {
+		s = strings.Replace(s, "://", ":", 1)
+	}
+	return
+}
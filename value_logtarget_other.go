@@ -0,0 +1,17 @@
+//go:build windows || plan9
+
+package flags
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+func openSyslog(network, addr string, facility int, tag string) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("syslog is not supported on %s", runtime.GOOS)
+}
+
+func openJournald(tag string) (io.WriteCloser, error) {
+	return nil, fmt.Errorf("journald is not supported on %s", runtime.GOOS)
+}
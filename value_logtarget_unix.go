@@ -0,0 +1,58 @@
+//go:build !windows && !plan9
+
+package flags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log/syslog"
+	"net"
+	"strings"
+)
+
+func openSyslog(network, addr string, facility int, tag string) (io.WriteCloser, error) {
+	return syslog.Dial(network, addr, syslog.Priority(facility<<3)|syslog.LOG_INFO, tag)
+}
+
+const journalSocket = "/run/systemd/journal/socket"
+
+// 按 journald 原生协议发送日志
+type journalWriter struct {
+	conn *net.UnixConn
+	tag  string
+}
+
+func openJournald(tag string) (io.WriteCloser, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journalWriter{conn: conn, tag: tag}, nil
+}
+
+func (w *journalWriter) Write(p []byte) (n int, err error) {
+	var buf bytes.Buffer
+	journalField(&buf, "SYSLOG_IDENTIFIER", w.tag)
+	journalField(&buf, "PRIORITY", "6")
+	journalField(&buf, "MESSAGE", strings.TrimSuffix(string(p), "\n"))
+
+	if _, err = w.conn.Write(buf.Bytes()); err != nil {
+		return
+	}
+	return len(p), nil
+}
+
+func (w *journalWriter) Close() error { return w.conn.Close() }
+
+// 值中有换行时按二进制格式写入: KEY\n<小端 uint64 长度><值>\n
+func journalField(buf *bytes.Buffer, key, val string) {
+	if !strings.Contains(val, "\n") {
+		buf.WriteString(key + "=" + val + "\n")
+		return
+	}
+
+	buf.WriteString(key + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(val)))
+	buf.WriteString(val + "\n")
+}
@@ -0,0 +1,59 @@
+package flags
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 为之后绑定的所有 bool 参数同时注册 --no-<name> 参数，也可以通过字段标签 `negatable:"true"` 单独开启
+func Negatable(flags ...*FlagSet) { metaOf(flagSet(flags)).negatable = true }
+
+// 参数最后一次是否通过 --no-<name> 设置
+func Negated(name string, flags ...*FlagSet) bool {
+	if f := flagSet(flags).Lookup(name); f != nil {
+		if v, ok := f.Value.(*value); ok {
+			return v.negated
+		}
+	}
+	return false
+}
+
+// --no-<name> 参数的值，设置时对目标取反
+type negValue struct{ target *value }
+
+func (n *negValue) String() string { return "false" }
+func (n *negValue) Type() string   { return "bool" }
+func (n *negValue) Set(s string) (err error) {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return
+	}
+	if err = n.target.Set(strconv.FormatBool(!b)); err == nil {
+		n.target.negated = true
+	}
+	return
+}
+
+func isNegatable(set *FlagSet, field *FlagField) bool {
+	return field.Value.IsBool() && (metaOf(set).negatable || tagBool(field.Field.Tag, _TAG_NEGATABLE))
+}
+
+func bindNegatable(set *FlagSet, field *FlagField, owner string) (err error) {
+	if !isNegatable(set, field) {
+		return
+	}
+
+	name := "no-" + field.Name
+	if err = checkName(set, name); err != nil {
+		return
+	}
+	if err = checkDuplicate(set, name, owner); err != nil {
+		return
+	}
+
+	item := set.VarPF(&negValue{target: field.Value}, name, "", fmt.Sprintf("取消 --%s", field.Name))
+	item.NoOptDefVal = "true"
+	item.Hidden = field.Hidden
+	metaOf(set).owners[name] = owner
+	return
+}
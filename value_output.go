@@ -0,0 +1,71 @@
+package flags
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// 输出目标: stdout, stderr, `-`(同 stdout) 或者文件路径
+//
+// 文件路径以 `>>` 开头时追加写入，以 `>` 开头或者不带前缀时清空后写入，如 `>>app.log`
+type Output struct {
+	Name   string // stdout, stderr 或者文件路径
+	Append bool
+}
+
+func (o Output) String() string { return rFormatOutput(o) }
+
+func (o Output) IsStd() bool { return o.Name == "stdout" || o.Name == "stderr" }
+
+// 打开输出目标，Name 为空时使用 stdout，关闭 stdout, stderr 不会关闭进程的标准输出
+func (o Output) Open() (io.WriteCloser, error) {
+	switch o.Name {
+	case "", "stdout":
+		return nopWriteCloser{os.Stdout}, nil
+	case "stderr":
+		return nopWriteCloser{os.Stderr}, nil
+	}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if o.Append {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	return os.OpenFile(o.Name, flag, 0o644)
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func init() {
+	Extend(rParseOutput, rFormatOutput)
+}
+
+func rParseOutput(s string) (o Output, err error) {
+	switch s = strings.TrimSpace(s); {
+	case s == "":
+	case s == "-" || s == "stdout":
+		o.Name = "stdout"
+	case s == "stderr":
+		o.Name = "stderr"
+	case strings.HasPrefix(s, ">>"):
+		o.Name, o.Append = strings.TrimSpace(s[2:]), true
+	case strings.HasPrefix(s, ">"):
+		o.Name = strings.TrimSpace(s[1:])
+	default:
+		o.Name = s
+	}
+
+	if o.Name != "" && !o.IsStd() {
+		o.Name, err = expandPath(o.Name)
+	}
+	return
+}
+
+func rFormatOutput(in Output) string {
+	if in.Append {
+		return ">>" + in.Name
+	}
+	return in.Name
+}
@@ -0,0 +1,108 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// 文件路径，解析时展开 `~` 并转换为绝对路径
+type Path string
+
+func (p Path) String() string { return string(p) }
+
+func init() {
+	Extend(rParsePath, rFormatPath)
+}
+
+func rParsePath(s string) (p Path, err error) {
+	s, err = expandPath(s)
+	return Path(s), err
+}
+
+func rFormatPath(in Path) string { return string(in) }
+
+// 展开 `~` 和 `~/` 开头的路径，并转换为绝对路径
+func expandPath(s string) (string, error) {
+	if s == "" {
+		return s, nil
+	}
+
+	if s == "~" || strings.HasPrefix(s, "~/") || strings.HasPrefix(s, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return s, fmt.Errorf("expand %s: %w", s, err)
+		}
+		s = filepath.Join(home, s[1:])
+	}
+
+	return filepath.Abs(s)
+}
+
+// 按路径标签检查路径
+//
+//	existing:      路径必须存在
+//	existing-file: 必须是已存在的文件
+//	existing-dir:  必须是已存在的目录
+//	creatable:     路径已存在，或者上级目录存在可以创建
+func checkPath(s, mode string) error {
+	fi, err := os.Stat(s)
+	switch mode {
+	case "existing":
+		if err != nil {
+			return fmt.Errorf("path %s not exists", s)
+		}
+	case "existing-file":
+		if err != nil || fi.IsDir() {
+			return fmt.Errorf("file %s not exists", s)
+		}
+	case "existing-dir":
+		if err != nil || !fi.IsDir() {
+			return fmt.Errorf("directory %s not exists", s)
+		}
+	case "creatable":
+		if err == nil {
+			return nil
+		}
+		if dir := filepath.Dir(s); !isDir(dir) {
+			return fmt.Errorf("can not create %s: directory %s not exists", s, dir)
+		}
+	case "", "true":
+	default:
+		return fmt.Errorf("unknown path check: %s", mode)
+	}
+	return nil
+}
+
+func isDir(s string) bool {
+	fi, err := os.Stat(s)
+	return err == nil && fi.IsDir()
+}
+
+// 标签 `path:"existing-file"`，string 或 Path 字段展开路径并检查，标签值为 true 时只展开不检查
+func fieldPath(field *FlagField) {
+	mode := getTag(field.Field.Tag, _TAG_PATH)
+	if mode == "" || mode == "false" {
+		return
+	}
+
+	if baseType(field.Value.typ).Kind() != reflect.String {
+		return
+	}
+
+	parse := func(s string) (string, error) {
+		if s == "" {
+			return s, nil
+		}
+		p, err := expandPath(s)
+		if err != nil {
+			return s, err
+		}
+		return p, checkPath(p, mode)
+	}
+
+	field.Value.setHooks(parse, nil)
+	field.Value.display = "path"
+}
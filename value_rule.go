@@ -0,0 +1,181 @@
+package flags
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// validate 标签中的一条规则，如 min=1
+type rule struct {
+	name string
+	arg  string
+	re   *regexp.Regexp
+	err  error
+}
+
+// 解析 validate 标签，规则以 `,` 分隔，regexp 规则会占用剩余的全部内容
+//
+//	validate:"min=1,max=65535"
+//	validate:"oneof=debug info warn error"
+//	validate:"len=6,regexp=^[0-9a-f]+$"
+func parseRules(tag string) (rules []rule) {
+	for tag != "" {
+		var s string
+		if strings.HasPrefix(tag, "regexp=") {
+			s, tag = tag, ""
+		} else {
+			s, tag, _ = strings.Cut(tag, ",")
+		}
+
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+
+		r := rule{}
+		r.name, r.arg, _ = strings.Cut(s, "=")
+		if r.name == "regexp" {
+			r.re, r.err = compileRule(r.arg)
+		}
+		rules = append(rules, r)
+	}
+	return
+}
+
+var ruleRegexps sync.Map // 同一个表达式只编译一次
+
+func compileRule(expr string) (*regexp.Regexp, error) {
+	if re, ok := ruleRegexps.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err == nil {
+		ruleRegexps.Store(expr, re)
+	}
+	return re, err
+}
+
+// 按规则校验单个值，切片和指针按元素类型校验，conv 不为空时比较大小前先转换输入的值
+func validateRules(t reflect.Type, s string, rules []rule, conv func(string) (string, error)) (err error) {
+	if len(rules) == 0 {
+		return
+	}
+
+	t = baseType(t)
+
+	parse := func(s string) (v reflect.Value, err error) {
+		if conv != nil {
+			if s, err = conv(s); err != nil {
+				return
+			}
+		}
+		v = reflect.New(t).Elem()
+		err = rSets(v, s)
+		return
+	}
+
+	for _, r := range rules {
+		switch r.name {
+		case "min", "max", "len":
+			var c int
+			if c, err = compareRule(t, s, r.arg, parse); err != nil {
+				return
+			}
+			switch {
+			case r.name == "min" && c < 0:
+				return fmt.Errorf("must be >= %s", r.arg)
+			case r.name == "max" && c > 0:
+				return fmt.Errorf("must be <= %s", r.arg)
+			case r.name == "len" && c != 0:
+				return fmt.Errorf("length must be %s", r.arg)
+			}
+		case "regexp":
+			if r.err != nil {
+				return fmt.Errorf("invalid regexp rule: %w", r.err)
+			}
+			if !r.re.MatchString(s) {
+				return fmt.Errorf("must match %s", r.arg)
+			}
+		case "oneof":
+			if opts := strings.Fields(r.arg); !sliceContains(opts, s) {
+				return fmt.Errorf("must be one of [%s]", strings.Join(opts, ", "))
+			}
+		case "url":
+			if u, e := url.Parse(s); e != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("must be an absolute url")
+			}
+		case "schemes":
+			if u, e := url.Parse(s); e != nil || !sliceContains(strings.Fields(r.arg), strings.ToLower(u.Scheme)) {
+				return fmt.Errorf("url scheme must be one of [%s]", strings.Join(strings.Fields(r.arg), ", "))
+			}
+		case "file-exists":
+			if fi, e := os.Stat(s); e != nil || fi.IsDir() {
+				return fmt.Errorf("file %s not exists", s)
+			}
+		case "dir-exists":
+			if fi, e := os.Stat(s); e != nil || !fi.IsDir() {
+				return fmt.Errorf("directory %s not exists", s)
+			}
+		default:
+			return fmt.Errorf("unknown validate rule: %s", r.name)
+		}
+	}
+	return
+}
+
+// 比较值和规则参数的大小，字符串比较长度，数字(包括 time.Duration 等扩展类型)比较数值
+func compareRule(t reflect.Type, s, arg string, parse func(string) (reflect.Value, error)) (c int, err error) {
+	if t.Kind() == reflect.String {
+		limit, e := strconv.Atoi(arg)
+		if e != nil {
+			return 0, fmt.Errorf("invalid rule argument: %s", arg)
+		}
+		return cmp(utf8.RuneCountInString(s), limit), nil
+	}
+
+	v, err := parse(s)
+	if err != nil {
+		return
+	}
+	a, err := parse(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rule argument: %s", arg)
+	}
+
+	switch {
+	case isIntKind(t.Kind()):
+		return cmp(v.Int(), a.Int()), nil
+	case isUintKind(t.Kind()):
+		return cmp(v.Uint(), a.Uint()), nil
+	case isFloatKind(t.Kind()):
+		return cmp(v.Float(), a.Float()), nil
+	default:
+		return 0, fmt.Errorf("can not compare %s", rType(t))
+	}
+}
+
+func cmp[T int | int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func sliceContains[T comparable](s []T, v T) bool {
+	for _, it := range s {
+		if it == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package flags
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 结构体切片字段，如 Upstreams []Upstream，元素通过 --upstreams.0.host 的形式单独设置
+type structSlice struct {
+	name  string
+	v     reflect.Value
+	items []*itemFlag
+}
+
+// 结构体切片中某一个元素的某一个字段
+type itemFlag struct {
+	index int
+	field *FlagField // 绑定在临时元素上
+}
+
+func isStructSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || isKnown(t) {
+		return false
+	}
+	if t = t.Elem(); t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isKnown(t)
+}
+
+// 找出结构体中的结构体切片字段，命名规则和 parseStruct 相同
+func structSlices(r reflect.Value, prefix string) (out []*structSlice) {
+	for i, t := 0, r.Type(); i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		flagTag := getTag(f.Tag, _TAG_FLAG)
+		if flagTag == "-" {
+			continue
+		}
+
+		name := strings.ToLower(f.Name)
+		if tags := fieldSpilt(flagTag); len(tags) > 0 {
+			name = tags[0]
+		}
+
+		fv := r.Field(i)
+		switch {
+		case f.Anonymous:
+			if fv = reflect.Indirect(fv); fv.IsValid() && fv.Kind() == reflect.Struct {
+				out = append(out, structSlices(fv, prefix)...)
+			}
+		case f.Type.Kind() == reflect.Struct && f.Type.Name() == "":
+			out = append(out, structSlices(fv, prefix+name+".")...)
+		case isStructSlice(f.Type):
+			out = append(out, &structSlice{name: prefix + name, v: fv})
+		}
+	}
+	return
+}
+
+// 解析前找出命令行中的 --name.<index>.<field> 参数并注册
+func defineItemFlags(set *FlagSet, args []string) error {
+	slices := metaOf(set).slices
+	if len(slices) == 0 {
+		return nil
+	}
+
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+
+		name, _, _ := strings.Cut(arg[2:], "=")
+		if set.Lookup(name) != nil {
+			continue
+		}
+
+		for _, s := range slices {
+			rest, ok := strings.CutPrefix(name, s.name+".")
+			if !ok {
+				continue
+			}
+
+			idx, sub, _ := strings.Cut(rest, ".")
+			index, err := strconv.Atoi(idx)
+			if err != nil || index < 0 {
+				return fmt.Errorf("invalid index %q in flag --%s", idx, name)
+			}
+
+			if err = s.define(set, name, index, sub); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func (s *structSlice) define(set *FlagSet, name string, index int, sub string) error {
+	et := s.v.Type().Elem()
+	if et.Kind() == reflect.Pointer {
+		et = et.Elem()
+	}
+
+	fields, err := ParseStruct(reflect.New(et), true)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if field.Name != sub {
+			continue
+		}
+
+		item := set.VarPF(field.Value, name, "", field.Usage)
+		if field.Value.IsBool() {
+			item.NoOptDefVal = "true"
+		}
+		if field.NoOptDefVal != "" {
+			item.NoOptDefVal = field.NoOptDefVal
+		}
+		s.items = append(s.items, &itemFlag{index: index, field: field})
+		return nil
+	}
+
+	return fmt.Errorf("unknown flag: --%s", name)
+}
+
+// 解析完成后(配置文件已经加载)，把命令行设置的元素字段写入切片，切片长度不够时补齐
+func applyItemFlags(set *FlagSet) error {
+	for _, s := range metaOf(set).slices {
+		for _, it := range s.items {
+			if !it.field.Value.changed {
+				continue
+			}
+
+			for s.v.Len() <= it.index {
+				el := reflect.New(s.v.Type().Elem()).Elem()
+				if el.Kind() == reflect.Pointer {
+					el.Set(reflect.New(el.Type().Elem()))
+				}
+				s.v.Set(reflect.Append(s.v, el))
+			}
+
+			el := s.v.Index(it.index)
+			if el.Kind() == reflect.Pointer && el.IsNil() {
+				el.Set(reflect.New(el.Type().Elem()))
+			}
+
+			fields, err := ParseStruct(reflect.Indirect(el).Addr(), true)
+			if err != nil {
+				return err
+			}
+			for _, field := range fields {
+				if field.Name == it.field.Name {
+					field.Referer.Set(it.field.Referer)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -4,45 +4,132 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
 func StructBind(structPtr any, flags ...*FlagSet) {
-	if len(flags) == 0 {
-		flags = append(flags, Default())
+	if err := StructBindE(structPtr, flags...); err != nil {
+		panic(err)
 	}
+}
+
+// 同 StructBind，出错时返回错误而不是 panic
+func StructBindE(structPtr any, flags ...*FlagSet) (err error) {
+	return structBind(structPtr, flagSet(flags), bindOptions{})
+}
 
-	v := reflect.Indirect(reflect.ValueOf(structPtr))
+func structBind(structPtr any, set *FlagSet, o bindOptions) (err error) {
+	v, err := structValue(structPtr)
+	if err != nil {
+		return
+	}
 
 	if !v.CanSet() {
-		panic(fmt.Errorf("cannot set %T", structPtr))
+		return fmt.Errorf("pass a non-nil pointer to a struct; got %T", structPtr)
 	}
 
 	fields, err := ParseStruct(v, true)
 	if err != nil {
-		panic(err)
+		return
 	}
 
+	// 同一个参数集合上的绑定串行执行，可以在多个 goroutine 中绑定
+	meta := metaOf(set)
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+
+	owner := func(field *FlagField) string { return v.Type().String() + "." + field.Field.Name }
 	for _, field := range fields {
-		field.UpdateFromEnv()
+		prefixField(field, o.prefix)
+	}
+	if err = checkFields(set, fields, owner); err != nil {
+		return
+	}
+	meta.structs = append(meta.structs, v)
 
-		usage := field.Usage
-		if usage == "" {
-			usage = field.Field.Name
+	for _, field := range fields {
+		if err = bindField(set, field, owner(field)); err != nil {
+			return
 		}
+	}
+
+	meta.slices = append(meta.slices, structSlices(v, o.prefix)...)
+	return
+}
+
+// owner 为参数所属的模块，用于冲突报告
+func bindField(set *FlagSet, field *FlagField, owner string) (err error) {
+	meta := metaOf(set)
+	if err = checkName(set, field.Name); err != nil {
+		return
+	}
+	if err = checkDuplicate(set, field.Name, owner); err != nil {
+		return
+	}
+	if err = checkShorthand(set, field, owner); err != nil {
+		return
+	}
+	if err = checkPriority(field); err != nil {
+		return
+	}
 
+	field.Value.flags = set
+	if !meta.inspect {
+		fieldDefaultFrom(field)
+		fieldDownward(set, field)
 		if len(field.Env) > 0 {
-			usage += fmt.Sprintf(" (env: %s)", strings.Join(field.Env, ", "))
+			start := time.Now()
+			field.UpdateFromEnv()
+			meta.stats.load(SourceEnv, start)
 		}
+	}
 
-		item := flagSet(flags).VarPF(field.Value, field.Name, field.Shorthand, usage)
-		item.Deprecated = field.Deprecated
-		item.ShorthandDeprecated = field.ShortDeprecated
+	usage := field.Usage
+	if usage == "" {
+		usage = field.Field.Name
+	}
 
-		fv := reflect.Indirect(field.Value.v)
-		if fv.IsValid() && fv.Kind() == reflect.Bool {
-			item.NoOptDefVal = "true"
+	if len(field.Env) > 0 {
+		if field.Value.envFirst {
+			usage += fmt.Sprintf(" (env: %s, 优先于命令行参数)", strings.Join(field.Env, ", "))
+		} else {
+			usage += fmt.Sprintf(" (env: %s)", strings.Join(field.Env, ", "))
 		}
 	}
+
+	if e := getTag(field.Field.Tag, _TAG_EXAMPLE); e != "" {
+		usage += fmt.Sprintf(" (example: %s)", e)
+	}
+
+	item := set.VarPF(field.Value, field.Name, field.Shorthand, usage)
+	item.Deprecated = field.Deprecated
+	item.ShorthandDeprecated = field.ShortDeprecated
+	item.Hidden = field.Hidden
+
+	fv := reflect.Indirect(field.Value.v)
+	if fv.IsValid() && fv.Kind() == reflect.Bool {
+		item.NoOptDefVal = "true"
+	}
+	if field.Value.count {
+		item.NoOptDefVal = "+1"
+	}
+	if field.NoOptDefVal != "" {
+		item.NoOptDefVal = field.NoOptDefVal
+	}
+
+	if err = bindNegatable(set, field, owner); err != nil {
+		return
+	}
+	if err = bindAliases(set, field, item, owner); err != nil {
+		return
+	}
+
+	fieldPrompt(set, field)
+	warnEnvCollision(set, field, owner)
+	meta.fields[item.Name] = field
+	meta.owners[item.Name] = owner
+
+	return fieldGroups(set, field)
 }
 
 func StructPrint(structPtr any, print func(s string)) {
@@ -60,7 +147,11 @@ func StructPrint(structPtr any, print func(s string)) {
 	}
 
 	for _, f := range fields {
-		print(fmt.Sprintf("%-*s | %s", max, f.Name, strings.Join(f.Value.Args(), ", ")))
+		val := strings.Join(f.Value.Args(), ", ")
+		if f.Value.secret {
+			val = redactValue(val)
+		}
+		print(fmt.Sprintf("%-*s | %s", max, f.Name, val))
 	}
 }
 
@@ -1,13 +1,36 @@
 package flags
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"text/template"
 	"time"
+	"unicode"
 
 	"github.com/spf13/pflag"
 )
@@ -136,10 +159,13 @@ func metaPrinter(printf func(string, ...any)) func(name string, rv reflect.Value
 		"zero",
 	}
 
-	headerPrint := sync.OnceFunc(func() {
-		printf("| %s |", strings.Join(headers, " | "))
-		printf("|%s|-------", strings.Join(sliceMap(headers, func(s string) string { return strings.Repeat("-", len(s)+2) }), "|"))
-	})
+	var once sync.Once
+	headerPrint := func() {
+		once.Do(func() {
+			printf("| %s |", strings.Join(headers, " | "))
+			printf("|%s|-------", strings.Join(sliceMap(headers, func(s string) string { return strings.Repeat("-", len(s)+2) }), "|"))
+		})
+	}
 
 	return func(name string, rv reflect.Value, rt reflect.Type) {
 		headerPrint()
@@ -198,3 +224,3980 @@ func sliceMap[S any, R any](s []S, f func(S) R) []R {
 	}
 	return r
 }
+
+func TestRawArgs(t *testing.T) {
+	set := pflag.NewFlagSet("raw", pflag.ContinueOnError)
+	set.IntP("port", "p", 0, "")
+	set.BoolP("debug", "d", false, "")
+	set.StringP("name", "n", "", "")
+
+	args := []string{"-p", "80", "--port=81", "-dnx", "pos", "--name", "y", "--", "--port", "82"}
+	if err := ParseFlags(set, args); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{
+		"port":  "-p 80 --port=81",
+		"debug": "-dnx",
+		"name":  "-dnx --name y",
+	} {
+		if got := strings.Join(RawArgs(name, set), " "); got != want {
+			t.Errorf("RawArgs(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRawArgsNoInterspersed(t *testing.T) {
+	set := pflag.NewFlagSet("raw", pflag.ContinueOnError)
+	set.Duration("timeout", 0, "")
+	Interspersed(false, set)
+
+	if err := ParseFlags(set, []string{"--timeout", "1s", "run", "--timeout", "2s", "--child-flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := RawArgs("timeout", set); !reflect.DeepEqual(got, []string{"--timeout", "1s"}) {
+		t.Errorf("RawArgs = %q", got)
+	}
+	if got := UnknownArgs(set); len(got) != 0 {
+		t.Errorf("UnknownArgs = %q", got)
+	}
+	if got := set.Args(); !reflect.DeepEqual(got, []string{"run", "--timeout", "2s", "--child-flag"}) {
+		t.Errorf("Args = %q", got)
+	}
+}
+
+func TestGenMarkdown(t *testing.T) {
+	var cfg struct {
+		Listen string `flag:"listen,l,LISTEN" usage:"监听地址" json:"listen_addr"`
+		Debug  bool
+	}
+	cfg.Listen = ":80"
+
+	set := pflag.NewFlagSet("md", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+
+	var buf strings.Builder
+	if err := GenMarkdown(&buf, set); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "| `-l`, `--listen` | `LISTEN` | `listen_addr` | `:80` | 监听地址 |"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("GenMarkdown missing row %q:\n%s", want, buf.String())
+	}
+}
+
+func TestHiddenDeprecated(t *testing.T) {
+	var cfg struct {
+		Addr   string `flag:"addr,a" deprecated:"use --listen, use -l"`
+		Listen string `flag:"listen,l"`
+		Secret string `hidden:"true"`
+	}
+
+	set := pflag.NewFlagSet("tags", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+
+	if f := set.Lookup("addr"); f.Deprecated != "use --listen" || f.ShorthandDeprecated != "use -l" {
+		t.Errorf("deprecated = %q, %q", f.Deprecated, f.ShorthandDeprecated)
+	}
+	if f := set.Lookup("secret"); !f.Hidden {
+		t.Errorf("secret should be hidden")
+	}
+}
+
+func TestGroups(t *testing.T) {
+	type Config struct {
+		JSON bool   `flag:"json" exclusive:"format"`
+		YAML bool   `flag:"yaml" exclusive:"format"`
+		File string `onerequired:"source"`
+		URL  string `onerequired:"source"`
+		TLS  bool   `flag:"tls" requires:"tls-cert"`
+		Cert string `flag:"tls-cert"`
+	}
+
+	for _, c := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"--file", "a"}, true},
+		{[]string{"--json", "--url", "b"}, true},
+		{[]string{"--json"}, false},
+		{[]string{"--json", "--yaml", "--file", "a"}, false},
+		{[]string{"--file", "a", "--tls"}, false},
+		{[]string{"--file", "a", "--tls", "--tls-cert", "c"}, true},
+	} {
+		set := pflag.NewFlagSet("groups", pflag.ContinueOnError)
+		set.SetOutput(io.Discard)
+		StructBind(&Config{}, set)
+		err := ParseFlags(set, c.args)
+		if (err == nil) != c.ok {
+			t.Errorf("ParseFlags(%q) = %v", c.args, err)
+		}
+	}
+}
+
+func TestSetAll(t *testing.T) {
+	var cfg struct {
+		Port  int
+		Hosts []string
+	}
+
+	set := pflag.NewFlagSet("setall", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+
+	if err := SetAll(set, map[string]string{"port": "80", "hosts": "a,b"}, SourceConfig); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 80 || strings.Join(cfg.Hosts, ",") != "a,b" || set.Changed("port") {
+		t.Errorf("SetAll = %+v", cfg)
+	}
+
+	if err := SetAll(set, map[string]string{"port": "81", "hosts": "c"}, SourceFlag); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 81 || strings.Join(cfg.Hosts, ",") != "c" || !set.Changed("port") {
+		t.Errorf("SetAll = %+v", cfg)
+	}
+
+	if err := SetAll(set, map[string]string{"port": "x", "hosts": "d"}, SourceFlag); err == nil || cfg.Hosts[0] != "c" {
+		t.Errorf("SetAll should fail without changes, err=%v, cfg=%+v", err, cfg)
+	}
+}
+
+type validateConfig struct {
+	Min int
+	Max int
+}
+
+func (c *validateConfig) Validate() error {
+	if c.Min > c.Max {
+		return fmt.Errorf("min(%d) > max(%d)", c.Min, c.Max)
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	var cfg struct{ validateConfig }
+
+	set := pflag.NewFlagSet("validate", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if err := ParseFlags(set, []string{"--min", "3", "--max", "2"}); err == nil {
+		t.Errorf("Validate should fail")
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	for _, c := range []struct {
+		typ   reflect.Type
+		s     string
+		rules string
+		ok    bool
+	}{
+		{reflect.TypeOf(0), "80", "min=1,max=65535", true},
+		{reflect.TypeOf(0), "0", "min=1,max=65535", false},
+		{reflect.TypeOf(time.Duration(0)), "2s", "min=1s", true},
+		{reflect.TypeOf(time.Duration(0)), "500ms", "min=1s", false},
+		{reflect.TypeOf([]string{}), "warn", "oneof=debug info warn", true},
+		{reflect.TypeOf(""), "trace", "oneof=debug info warn", false},
+		{reflect.TypeOf(""), "abc", "len=3,regexp=^[a-c]{1,3}$", true},
+		{reflect.TypeOf(""), "abd", "len=3,regexp=^[a-c]{1,3}$", false},
+		{reflect.TypeOf(""), "https://example.com", "url", true},
+		{reflect.TypeOf(""), "example.com", "url", false},
+		{reflect.TypeOf(""), ".", "dir-exists", true},
+		{reflect.TypeOf(""), ".", "file-exists", false},
+	} {
+		if err := validateRules(c.typ, c.s, parseRules(c.rules), nil); (err == nil) != c.ok {
+			t.Errorf("validate %q with %q: %v", c.s, c.rules, err)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	var cfg struct {
+		Port  int
+		Hosts []string
+	}
+
+	set := pflag.NewFlagSet("sync", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if err := ParseFlags(set, []string{"--port", "0", "--hosts", "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Hosts = append(cfg.Hosts, "b")
+	if values := Values(set); values["port"] != "0" || values["hosts"] != "a,b" {
+		t.Errorf("Values = %v", values)
+	}
+}
+
+func TestCount(t *testing.T) {
+	var cfg struct {
+		Verbose int `flag:"verbose,v" count:"true"`
+	}
+
+	set := pflag.NewFlagSet("count", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if err := ParseFlags(set, []string{"-vvv", "--verbose"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Verbose != 4 {
+		t.Errorf("Verbose = %d, want 4", cfg.Verbose)
+	}
+}
+
+func TestStructBindE(t *testing.T) {
+	var cfg struct{ Port int }
+	var nilPtr *struct{ Port int }
+
+	for _, src := range []any{nil, cfg, nilPtr, map[string]int{}, &map[string]int{}} {
+		set := pflag.NewFlagSet("bind", pflag.ContinueOnError)
+		if err := StructBindE(src, set); err == nil {
+			t.Errorf("StructBindE(%T) should fail", src)
+		} else if !strings.HasPrefix(err.Error(), "pass a non-nil pointer to a struct") {
+			t.Errorf("StructBindE(%T) = %v", src, err)
+		}
+	}
+}
+
+func TestNegatable(t *testing.T) {
+	cfg := struct {
+		Color bool `negatable:"true"`
+	}{Color: true}
+
+	set := pflag.NewFlagSet("neg", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if err := ParseFlags(set, []string{"--no-color"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Color || !Negated("color", set) {
+		t.Errorf("Color = %v, Negated = %v", cfg.Color, Negated("color", set))
+	}
+}
+
+func TestInlineStruct(t *testing.T) {
+	var cfg struct {
+		Opts struct {
+			Host string
+			Port int `flag:"port,p"`
+		}
+	}
+
+	set := pflag.NewFlagSet("inline", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if err := ParseFlags(set, []string{"--opts.host", "localhost", "-p", "80"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Opts.Host != "localhost" || cfg.Opts.Port != 80 {
+		t.Errorf("Opts = %+v", cfg.Opts)
+	}
+}
+
+func TestMergeOmitEmpty(t *testing.T) {
+	cfg := struct {
+		Port int    `json:"port" merge:"omitempty"`
+		Host string `json:"host"`
+	}{Port: 80, Host: "localhost"}
+
+	if err := LoadConfig(&cfg, "json", []byte(`{"port": 0, "host": ""}`)); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 80 || cfg.Host != "" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestNoOptDefVal(t *testing.T) {
+	var cfg struct {
+		Cache string `nooptdefval:"memory"`
+	}
+
+	set := pflag.NewFlagSet("noopt", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if err := ParseFlags(set, []string{"--cache"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Cache != "memory" {
+		t.Errorf("Cache = %q", cfg.Cache)
+	}
+}
+
+func TestSpec(t *testing.T) {
+	spec, err := ParseSpec("yaml", []byte(`
+flags:
+  - name: port
+    shorthand: p
+    type: int
+    default: 8080
+    usage: 监听端口
+    env: [PORT]
+  - name: hosts
+    type: strings
+    default: [a, b]
+  - name: timeout
+    type: duration
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := pflag.NewFlagSet("spec", pflag.ContinueOnError)
+	if err = SpecBind(spec, set); err != nil {
+		t.Fatal(err)
+	}
+	if err = ParseFlags(set, []string{"-p", "80", "--timeout", "1m"}); err != nil {
+		t.Fatal(err)
+	}
+	if values := Values(set); values["port"] != "80" || values["hosts"] != "a,b" || values["timeout"] != "1m" {
+		t.Errorf("Values = %v", values)
+	}
+
+	var buf strings.Builder
+	if err = GenSpecStruct(&buf, spec, "Config"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\tPort int `flag:\"port,p\" env:\"PORT\" usage:\"监听端口\"` // default: 8080\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("GenSpecStruct:\n%s", buf.String())
+	}
+}
+
+func TestTimeLayout(t *testing.T) {
+	cfg := struct {
+		Day   time.Time `layout:"02.01.2006"`
+		Since time.Time
+	}{Day: time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)}
+
+	set := pflag.NewFlagSet("time", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if f := set.Lookup("day"); f.DefValue != "02.01.2024" {
+		t.Errorf("DefValue = %q", f.DefValue)
+	}
+
+	var until time.Time
+	TimeVar(set, &until, "until", time.Time{}, "", "2006/01/02")
+
+	if err := ParseFlags(set, []string{"--day", "31.12.2023", "--since", "-24h", "--until", "2024/03/04"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Day.Format(time.DateOnly) != "2023-12-31" || until.Format(time.DateOnly) != "2024-03-04" {
+		t.Errorf("Day = %s, Until = %s", cfg.Day, until)
+	}
+	if d := time.Since(cfg.Since); d < 24*time.Hour || d > 25*time.Hour {
+		t.Errorf("Since = %s", cfg.Since)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	cfg := struct {
+		MaxUpload ByteSize `flag:"max-upload"`
+		Buffer    int64    `size:"true"`
+	}{Buffer: 4 << 10}
+
+	set := pflag.NewFlagSet("size", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if f := set.Lookup("buffer"); f.DefValue != "4KiB" {
+		t.Errorf("DefValue = %q", f.DefValue)
+	}
+
+	if err := ParseFlags(set, []string{"--max-upload", "50MiB", "--buffer", "1.5KB"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxUpload != 50<<20 || cfg.Buffer != 1500 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	for _, s := range []string{"20000000TiB", "9000000.5TiB", "18446744073709551615"} {
+		if _, err := rParseByteSize(s); err == nil {
+			t.Errorf("expected overflow error for %s", s)
+		}
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	spec, err := OpenAPISpec("yaml", []byte(`
+openapi: 3.0.0
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+          required: true
+          schema: {type: integer, format: int32, minimum: 1, maximum: 100}
+        - name: size
+          in: query
+          schema: {type: integer, format: int64, maximum: 1000000}
+        - name: status
+          in: query
+          schema:
+            type: array
+            items: {type: string, enum: [available, sold]}
+`), "listPets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := pflag.NewFlagSet("openapi", pflag.ContinueOnError)
+	if err = SpecBind(spec, set); err != nil {
+		t.Fatal(err)
+	}
+	if err = ParseFlags(set, []string{"--status", "sold"}); err == nil {
+		t.Errorf("limit should be required")
+	}
+	if err = set.Set("limit", "101"); err == nil {
+		t.Errorf("limit should be <= 100")
+	}
+	if err = set.Set("status", "pending"); err == nil {
+		t.Errorf("status should be one of enum")
+	}
+	if err = set.Set("size", "999999"); err != nil {
+		t.Errorf("size: %v", err)
+	}
+}
+
+func TestURL(t *testing.T) {
+	var cfg struct {
+		Endpoint url.URL `schemes:"http,https"`
+		Proxy    *url.URL
+	}
+
+	set := pflag.NewFlagSet("url", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if err := ParseFlags(set, []string{"--endpoint", "https://example.com/api", "--proxy", "socks5://127.0.0.1:1080"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Endpoint.Host != "example.com" || cfg.Proxy == nil || cfg.Proxy.Scheme != "socks5" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	for _, s := range []string{"ftp://example.com", "example.com", "http://"} {
+		if err := set.Set("endpoint", s); err == nil {
+			t.Errorf("endpoint %q should be invalid", s)
+		}
+	}
+}
+
+func TestPreset(t *testing.T) {
+	var cfg struct {
+		Port  int
+		Debug bool
+	}
+
+	set := pflag.NewFlagSet("preset", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if err := DefinePreset(set, "dev", map[string]string{"port": "8080", "debug": "true"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ParseFlags(set, []string{"--port", "9090", "--dev"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9090 || !cfg.Debug {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestNetip(t *testing.T) {
+	cfg := struct {
+		Addr   netip.Addr
+		Allow  []netip.Prefix
+		Listen netip.AddrPort
+	}{Listen: netip.MustParseAddrPort("0.0.0.0:80")}
+
+	set := pflag.NewFlagSet("netip", pflag.ContinueOnError)
+	StructBind(&cfg, set)
+	if f := set.Lookup("listen"); f.DefValue != "0.0.0.0:80" || f.Value.Type() != "addrport" {
+		t.Errorf("listen = %q %q", f.DefValue, f.Value.Type())
+	}
+
+	if err := ParseFlags(set, []string{"--addr", "::1", "--allow", "10.0.0.0/8", "--allow", "fd00::/8", "--listen", "[::]:443"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr.String() != "::1" || len(cfg.Allow) != 2 || cfg.Listen.Port() != 443 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestNameConvention(t *testing.T) {
+	check := KebabCase(16, "x-")
+	for name, ok := range map[string]bool{
+		"max-conn":              true,
+		"db.max-conn":           true,
+		"max_conn":              false,
+		"MaxConn":               false,
+		"x-internal":            false,
+		"a-very-long-flag-name": false,
+	} {
+		if err := check(name); (err == nil) != ok {
+			t.Errorf("KebabCase(%q) = %v", name, err)
+		}
+	}
+
+	var cfg struct {
+		MaxConn int `flag:"max_conn"`
+	}
+	set := pflag.NewFlagSet("naming", pflag.ContinueOnError)
+	NameConvention(set, check)
+	if err := StructBindE(&cfg, set); err == nil {
+		t.Errorf("max_conn should be rejected")
+	}
+}
+
+func TestEnvCollisions(t *testing.T) {
+	type DB struct {
+		Host string `env:"HOST"`
+	}
+	type Web struct {
+		Listen string `env:"HOST"`
+	}
+
+	set := pflag.NewFlagSet("owners", pflag.ContinueOnError)
+	StructBind(&DB{}, set)
+	StructBind(&Web{}, set)
+
+	if c := EnvCollisions(set); strings.Join(c["HOST"], ",") != "host,listen" {
+		t.Errorf("EnvCollisions = %v", c)
+	}
+
+	var buf strings.Builder
+	OwnerReport(&buf, set)
+	if !strings.Contains(buf.String(), "HOST: --host (flags.DB.Host), --listen (flags.Web.Listen)") {
+		t.Errorf("OwnerReport:\n%s", buf.String())
+	}
+}
+
+func TestLocation(t *testing.T) {
+	var c struct {
+		TZ *time.Location
+	}
+
+	set := pflag.NewFlagSet("location", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if typ := set.Lookup("tz").Value.Type(); typ != "location" {
+		t.Errorf("type = %s", typ)
+	}
+	if err := set.Parse([]string{"--tz", "UTC"}); err != nil || c.TZ != time.UTC {
+		t.Errorf("tz = %v, err = %v", c.TZ, err)
+	}
+	if err := set.Parse([]string{"--tz", "Mars/Olympus"}); err == nil {
+		t.Errorf("expected error for unknown location")
+	}
+}
+
+func TestPath(t *testing.T) {
+	dir := t.TempDir()
+	file := dir + "/app.conf"
+	os.WriteFile(file, nil, 0o644)
+	home, _ := os.UserHomeDir()
+
+	var c struct {
+		Conf  string `path:"existing-file"`
+		Data  Path
+		Out   string `path:"creatable"`
+		Cache Path   `path:"existing-dir"`
+	}
+
+	set := pflag.NewFlagSet("path", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if typ := set.Lookup("data").Value.Type(); typ != "path" {
+		t.Errorf("type = %s", typ)
+	}
+
+	err := set.Parse([]string{"--conf", file, "--data", "~/x", "--out", dir + "/out.log", "--cache", dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Data != Path(home+"/x") || c.Conf != file || c.Cache != Path(dir) {
+		t.Errorf("got %+v", c)
+	}
+
+	for _, args := range [][]string{
+		{"--conf", dir},
+		{"--out", dir + "/missing/out.log"},
+		{"--cache", file},
+	} {
+		if err := set.Parse(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestPriority(t *testing.T) {
+	Init("priority", map[string]string{"APP_REGION": "cn-north", "APP_ZONE": "zone-a"})
+	defer Init("", nil)
+
+	var c struct {
+		Region string `env:"APP_REGION" priority:"env>flag"`
+		Zone   string `env:"APP_ZONE"`
+	}
+
+	set := pflag.NewFlagSet("priority", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if usage := set.Lookup("region").Usage; !strings.Contains(usage, "优先于命令行参数") {
+		t.Errorf("usage = %s", usage)
+	}
+
+	if err := ParseFlags(set, []string{"--region", "us-east", "--zone", "zone-b"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Region != "cn-north" || c.Zone != "zone-b" {
+		t.Errorf("got %+v", c)
+	}
+	if v := set.Lookup("region").Value.(*value); v.source != SourceEnv {
+		t.Errorf("source = %s", v.source)
+	}
+
+	var bad struct {
+		Region string `priority:"config>env"`
+	}
+	if err := StructBindE(&bad, pflag.NewFlagSet("bad", pflag.ContinueOnError)); err == nil || !strings.Contains(err.Error(), "priority") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestStructInspect(t *testing.T) {
+	Init("inspect", map[string]string{"APP_PORT": "9090"})
+	defer Init("", nil)
+
+	c := struct {
+		Port int `env:"APP_PORT" usage:"监听端口"`
+	}{Port: 8080}
+
+	set, err := StructInspect(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f := set.Lookup("port"); f == nil || f.DefValue != "8080" {
+		t.Fatalf("port = %+v", f)
+	}
+
+	var buf strings.Builder
+	if err = GenMarkdown(&buf, set); err != nil || !strings.Contains(buf.String(), "APP_PORT") {
+		t.Errorf("markdown = %s, err = %v", buf.String(), err)
+	}
+
+	set.Set("port", "1")
+	if c.Port != 8080 {
+		t.Errorf("original struct modified: %d", c.Port)
+	}
+
+	Release(set)
+	if lookupMeta(set) != nil {
+		t.Errorf("state not released")
+	}
+	before := len(metas)
+	if _, err = StructInspect(struct {
+		A, B int `flag:"a"`
+	}{}); err == nil || len(metas) != before {
+		t.Errorf("err = %v, state leaked: %d -> %d", err, before, len(metas))
+	}
+}
+
+func TestMap(t *testing.T) {
+	var c struct {
+		Header map[string][]string
+		Label  map[string]string
+		Limit  map[string]int
+	}
+
+	set := pflag.NewFlagSet("map", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if typ := set.Lookup("header").Value.Type(); typ != "string=strings" {
+		t.Errorf("type = %s", typ)
+	}
+
+	err := set.Parse([]string{
+		"--header", "Accept=json", "--header", "Accept=xml", "--header", "X-Id=1",
+		"--label", "app=web", "--label", "env=prod",
+		"--limit", "cpu=2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(c.Header, map[string][]string{"Accept": {"json", "xml"}, "X-Id": {"1"}}) {
+		t.Errorf("header = %v", c.Header)
+	}
+	if !reflect.DeepEqual(c.Label, map[string]string{"app": "web", "env": "prod"}) || c.Limit["cpu"] != 2 {
+		t.Errorf("label = %v, limit = %v", c.Label, c.Limit)
+	}
+	if args := set.Lookup("header").Value.(*value).gets(reflect.ValueOf(c.Header)); strings.Join(args, " ") != "Accept=json Accept=xml X-Id=1" {
+		t.Errorf("gets = %v", args)
+	}
+
+	if err := set.Parse([]string{"--limit", "cpu"}); err == nil {
+		t.Errorf("expected error without '='")
+	}
+	if err := set.Parse([]string{"--limit", "cpu=x"}); err == nil {
+		t.Errorf("expected error for invalid int")
+	}
+}
+
+func TestOutput(t *testing.T) {
+	dir := t.TempDir()
+
+	var c struct {
+		Out Output
+		Log Output
+	}
+
+	set := pflag.NewFlagSet("output", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if err := set.Parse([]string{"--out", "-", "--log", ">>" + dir + "/app.log"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Out.Name != "stdout" || !c.Log.Append || c.Log.Name != dir+"/app.log" {
+		t.Fatalf("got %+v", c)
+	}
+
+	for i := 0; i < 2; i++ {
+		w, err := c.Log.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, "line\n")
+		w.Close()
+	}
+
+	if data, _ := os.ReadFile(dir + "/app.log"); string(data) != "line\nline\n" {
+		t.Errorf("log = %q", data)
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	var c struct {
+		Extra json.RawMessage
+		Attrs map[string]any
+	}
+
+	set := pflag.NewFlagSet("json", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if err := set.Parse([]string{"--extra", `{"a": 1, "b": [1, 2]}`, "--attrs", `{"app":"web","replicas":3}`}); err != nil {
+		t.Fatal(err)
+	}
+	if string(c.Extra) != `{"a": 1, "b": [1, 2]}` || c.Attrs["app"] != "web" || c.Attrs["replicas"] != float64(3) {
+		t.Errorf("got extra=%s attrs=%v", c.Extra, c.Attrs)
+	}
+
+	if err := SetAll(set, map[string]string{"extra": `{"x":1,"y":2}`}, SourceConfig); err != nil || string(c.Extra) != `{"x":1,"y":2}` {
+		t.Errorf("SetAll extra = %s, err = %v", c.Extra, err)
+	}
+
+	for _, args := range [][]string{{"--extra", "{a:1}"}, {"--attrs", "[1]"}} {
+		if err := set.Parse(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestLogTarget(t *testing.T) {
+	var c struct {
+		Syslog  LogTarget
+		Journal LogTarget
+		Local   LogTarget
+	}
+
+	set := pflag.NewFlagSet("logtarget", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	err := set.Parse([]string{"--syslog", "syslog://localhost?facility=local0&tag=app", "--journal", "journald:", "--local", "syslog:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := LogTarget{Scheme: "syslog", Network: "udp", Addr: "localhost:514", Facility: "local0", Tag: "app"}
+	if c.Syslog != want || c.Journal.Scheme != "journald" || c.Local != (LogTarget{Scheme: "syslog"}) {
+		t.Errorf("got %+v", c)
+	}
+	if s := c.Syslog.String(); s != "syslog://localhost:514?facility=local0&tag=app" {
+		t.Errorf("String() = %s", s)
+	}
+	if s := c.Journal.String(); s != "journald:" {
+		t.Errorf("String() = %s", s)
+	}
+
+	for _, s := range []string{"http://localhost", "syslog://localhost?facility=nope", "syslog+sctp://localhost"} {
+		if err := set.Set("syslog", s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestBytesEncoding(t *testing.T) {
+	c := struct {
+		Raw    []byte
+		Key    []byte `encoding:"hex"`
+		Secret []byte `encoding:"base64"`
+	}{Key: []byte{0xde, 0xad}}
+
+	set := pflag.NewFlagSet("bytes", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if f := set.Lookup("key"); f.DefValue != "dead" || f.Value.Type() != "<hex>" {
+		t.Errorf("key default = %s, type = %s", f.DefValue, f.Value.Type())
+	}
+
+	if err := set.Parse([]string{"--raw", "a,b", "--key", "00ff", "--secret", "aGVsbG8"}); err != nil {
+		t.Fatal(err)
+	}
+	if string(c.Raw) != "a,b" || !reflect.DeepEqual(c.Key, []byte{0x00, 0xff}) || string(c.Secret) != "hello" {
+		t.Errorf("got %+v", c)
+	}
+
+	for _, args := range [][]string{{"--key", "xyz"}, {"--secret", "!!"}} {
+		if err := set.Parse(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+
+	var bad struct {
+		Key []byte `encoding:"base32"`
+	}
+	err := StructBindE(&bad, pflag.NewFlagSet("bytes", pflag.ContinueOnError))
+	if err == nil || !strings.Contains(err.Error(), `unsupported encoding "base32", want hex, base64 or raw`) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestCheckConflicts(t *testing.T) {
+	Init("conflicts", map[string]string{"APP_PORT": "9090", "APP_HOST": "db", "APP_TOKEN": "env-secret"})
+	defer Init("", nil)
+
+	type Config struct {
+		Port  int    `env:"APP_PORT"`
+		Host  string `env:"APP_HOST"`
+		Token string `env:"APP_TOKEN" secret:"true"`
+	}
+
+	set := pflag.NewFlagSet("conflicts", pflag.ContinueOnError)
+	StructBind(&Config{}, set)
+	CheckConflicts(false, set)
+	if err := ParseFlags(set, []string{"--port", "8080", "--host", "db"}); err != nil {
+		t.Errorf("warn mode: %v", err)
+	}
+
+	set = pflag.NewFlagSet("conflicts", pflag.ContinueOnError)
+	StructBind(&Config{}, set)
+	CheckConflicts(true, set)
+	err := ParseFlags(set, []string{"--port", "8080", "--host", "db"})
+	if err == nil || !strings.Contains(err.Error(), "--port: flag=8080, env APP_PORT=9090") || strings.Contains(err.Error(), "--host") {
+		t.Errorf("strict mode: %v", err)
+	}
+
+	set = pflag.NewFlagSet("conflicts", pflag.ContinueOnError)
+	StructBind(&Config{}, set)
+	CheckConflicts(true, set)
+	err = ParseFlags(set, []string{"--token", "cli-secret"})
+	if err == nil || !strings.Contains(err.Error(), "--token: flag=******, env APP_TOKEN=******") || strings.Contains(err.Error(), "secret") {
+		t.Errorf("secret: %v", err)
+	}
+}
+
+// 模拟 uuid.UUID: 值接收者 MarshalText，指针接收者 UnmarshalText
+type testUUID [16]byte
+
+func (u testUUID) MarshalText() ([]byte, error) {
+	h := hex.EncodeToString(u[:])
+	return []byte(h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]), nil
+}
+
+func (u *testUUID) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(strings.ReplaceAll(string(text), "-", ""))
+	if err != nil || len(b) != 16 {
+		return fmt.Errorf("invalid uuid: %s", text)
+	}
+	copy(u[:], b)
+	return nil
+}
+
+func TestTextUnmarshaler(t *testing.T) {
+	const id = "123e4567-e89b-12d3-a456-426614174000"
+
+	var def testUUID
+	def.UnmarshalText([]byte(id))
+
+	c := struct {
+		ID    testUUID
+		Peers []testUUID
+	}{ID: def}
+
+	set := pflag.NewFlagSet("text", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if f := set.Lookup("id"); f.DefValue != id || f.Value.Type() != "testuuid" {
+		t.Errorf("default = %s, type = %s", f.DefValue, f.Value.Type())
+	}
+
+	if err := set.Parse([]string{"--peers", id, "--peers", strings.ToUpper(id)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Peers) != 2 || c.Peers[0] != def || c.Peers[1] != def {
+		t.Errorf("peers = %v", c.Peers)
+	}
+
+	if err := set.Parse([]string{"--id", "nope"}); err == nil {
+		t.Errorf("expected error for invalid uuid")
+	}
+}
+
+func TestStructSlice(t *testing.T) {
+	type Upstream struct {
+		Host   string
+		Port   int
+		Backup bool
+	}
+
+	var c struct {
+		Upstreams []Upstream
+		Mirrors   []*Upstream
+		Name      string
+	}
+
+	file := t.TempDir() + "/app.json"
+	os.WriteFile(file, []byte(`{"upstreams": [{"host": "a", "port": 80}, {"host": "b", "port": 81}]}`), 0o644)
+
+	set := pflag.NewFlagSet("slices", pflag.ContinueOnError)
+	StructBind(&c, set)
+	BindFile(&c, "config", "c", "", "配置文件", set)
+	if set.Lookup("name") == nil {
+		t.Fatalf("fields after the struct slice are not bound")
+	}
+
+	err := ParseFlags(set, []string{"--config", file, "--upstreams.1.port=8081", "--upstreams.2.host", "c", "--mirrors.0.backup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Upstream{{"a", 80, false}, {"b", 8081, false}, {"c", 0, false}}
+	if !reflect.DeepEqual(c.Upstreams, want) {
+		t.Errorf("upstreams = %+v", c.Upstreams)
+	}
+	if len(c.Mirrors) != 1 || !c.Mirrors[0].Backup {
+		t.Errorf("mirrors = %+v", c.Mirrors)
+	}
+
+	set = pflag.NewFlagSet("slices", pflag.ContinueOnError)
+	StructBind(&c, set)
+	if err := ParseFlags(set, []string{"--upstreams.0.weight", "1"}); err == nil {
+		t.Errorf("expected error for unknown item field")
+	}
+}
+
+func TestOnSet(t *testing.T) {
+	c := struct {
+		Level string
+		Tags  []string
+	}{Level: "info"}
+
+	set := pflag.NewFlagSet("hooks", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	var changes []string
+	record := func(old, new string) { changes = append(changes, old+"->"+new) }
+	if err := OnSet("level", record, set); err != nil {
+		t.Fatal(err)
+	}
+	OnSet("tags", record, set)
+	if err := OnSet("missing", record, set); err == nil {
+		t.Errorf("expected error for unknown flag")
+	}
+
+	var parsed int
+	OnParsed(func(*FlagSet) { parsed++ }, set)
+
+	if err := ParseFlags(set, []string{"--level", "info", "--level", "debug", "--tags", "a", "--tags", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	SetAll(set, map[string]string{"level": "warn"}, SourceConfig)
+
+	if want := "info->debug ->a a->a,b debug->warn"; strings.Join(changes, " ") != want {
+		t.Errorf("changes = %v", changes)
+	}
+	if parsed != 1 {
+		t.Errorf("parsed = %d", parsed)
+	}
+}
+
+// 大结构体的标签解析
+func BenchmarkParseStruct(b *testing.B) {
+	fields := make([]reflect.StructField, 200)
+	for i := range fields {
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: reflect.TypeOf(""),
+			Tag:  reflect.StructTag(fmt.Sprintf(`flag:"f-%d,f,APP_F%d;APP_OLD_F%d" deprecated:"use --g-%d, g" usage:"field %d"`, i, i, i, i, i)),
+		}
+	}
+	v := reflect.New(reflect.StructOf(fields)).Interface()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseStruct(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// 大量环境变量和参数时的绑定耗时，每个参数的环境变量都要在 envUsers 索引中检查和记录
+func BenchmarkStructBindEnv(b *testing.B) {
+	for i := 0; i < 5000; i++ {
+		b.Setenv(fmt.Sprintf("BENCH_NOISE_%d", i), "x")
+	}
+
+	fields := make([]reflect.StructField, 500)
+	for i := range fields {
+		// 部分参数共用一个环境变量，冲突检查时索引中有多个参数
+		tag := fmt.Sprintf(`env:"BENCH_F%d,BENCH_OLD_F%d"`, i, i)
+		if i%50 == 0 {
+			tag = fmt.Sprintf(`env:"BENCH_F%d,BENCH_SHARED"`, i)
+		}
+		fields[i] = reflect.StructField{Name: fmt.Sprintf("F%d", i), Type: reflect.TypeOf(""), Tag: reflect.StructTag(tag)}
+		if i%10 == 0 {
+			b.Setenv(fmt.Sprintf("BENCH_F%d", i), "v")
+		}
+	}
+	typ := reflect.StructOf(fields)
+
+	devnull, _ := os.Open(os.DevNull)
+	stderr := os.Stderr
+	os.Stderr = devnull
+	defer func() { os.Stderr = stderr; devnull.Close() }()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set := &Set{pflag.NewFlagSet("bench", pflag.ContinueOnError)}
+		if err := StructBindE(reflect.New(typ).Interface(), set.FlagSet); err != nil {
+			b.Fatal(err)
+		}
+		if i == 0 && len(metaOf(set.FlagSet).envUsers["BENCH_SHARED"]) != 10 {
+			b.Fatalf("envUsers = %v", metaOf(set.FlagSet).envUsers["BENCH_SHARED"])
+		}
+		set.Release()
+	}
+}
+
+// 旧的基于 strings.FieldsFunc 的实现，作为分词的参照
+func refTokenize(s string, names bool) (out []string) {
+	for _, tok := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|' || (names && unicode.IsSpace(r))
+	}) {
+		if names {
+			tok = strings.Trim(tok, "-_")
+		} else {
+			tok = strings.TrimSpace(tok)
+		}
+		if tok != "" {
+			out = append(out, tok)
+		}
+	}
+	return
+}
+
+func FuzzTokenize(f *testing.F) {
+	for _, s := range []string{"", "a", "p,port;PORT|APP_PORT", " --max_conn , m ", "use --new, n", "a,,b||c", "中文, 说明;　x"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, names := range []bool{true, false} {
+			if got, want := tokenize(s, names), refTokenize(s, names); !reflect.DeepEqual(got, want) {
+				t.Errorf("tokenize(%q, %v) = %q, want %q", s, names, got, want)
+			}
+		}
+	})
+}
+
+// 帮助信息和解析时频繁调用的 String()
+func BenchmarkValueString(b *testing.B) {
+	c := struct {
+		Hosts []string
+		Port  int
+	}{Hosts: strings.Split("a,b,c,d,e,f,g,h,i,j,k,l,m,n,o,p", ","), Port: 80}
+
+	set := pflag.NewFlagSet("bench", pflag.ContinueOnError)
+	StructBind(&c, set)
+	hosts, port := set.Lookup("hosts").Value, set.Lookup("port").Value
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = hosts.String()
+		_ = port.String()
+	}
+}
+
+func TestValueStringDefault(t *testing.T) {
+	c := struct{ Port int }{Port: 80}
+
+	set := pflag.NewFlagSet("string", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	set.Parse([]string{"--port", "8080"})
+	if s := set.Lookup("port").Value.String(); s != "80" {
+		t.Errorf("String() = %s, want the default 80", s)
+	}
+}
+
+func TestParseStructArgs(t *testing.T) {
+	var c struct {
+		Port int `validate:"min=1"`
+		Host string
+	}
+
+	before := len(metas)
+	if err := ParseStructArgs(&c, []string{"--port", "8080", "--host", "db"}); err != nil || c.Port != 8080 || c.Host != "db" {
+		t.Errorf("got %+v, err = %v", c, err)
+	}
+	if err := ParseStructArgs(&c, []string{"--port", "0"}); err == nil {
+		t.Errorf("expected validation error")
+	}
+	if err := ParseStructArgs(c, nil); err == nil {
+		t.Errorf("expected error for non-pointer")
+	}
+	if pflag.CommandLine.Lookup("port") != nil {
+		t.Errorf("global flag set modified")
+	}
+	if len(metas) != before {
+		t.Errorf("flag set state leaked: %d -> %d", before, len(metas))
+	}
+
+	set := pflag.NewFlagSet("args", pflag.ContinueOnError)
+	set.Int("n", 0, "")
+	if err := ParseArgs([]string{"--n", "3"}, set); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := set.GetInt("n"); n != 3 {
+		t.Errorf("n = %d", n)
+	}
+}
+
+func TestParseFlagsContext(t *testing.T) {
+	file := t.TempDir() + "/app.json"
+	os.WriteFile(file, []byte(`{"port": 8080}`), 0o644)
+
+	var c struct{ Port int }
+
+	set := pflag.NewFlagSet("context", pflag.ContinueOnError)
+	StructBind(&c, set)
+	BindFile(&c, "config", "", "", "配置文件", set)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := ParseFlagsContext(ctx, set, []string{"--config", file}); err != nil || c.Port != 8080 {
+		t.Fatalf("port = %d, err = %v", c.Port, err)
+	}
+
+	cancel()
+	c.Port = 0
+	if err := ParseFlagsContext(ctx, set, []string{"--config", file}); err != context.Canceled || c.Port != 0 {
+		t.Errorf("port = %d, err = %v", c.Port, err)
+	}
+
+	set = pflag.NewFlagSet("context", pflag.ContinueOnError)
+	BindFile(&c, "config", "", "", "配置文件", set)
+	metaOf(set).ctx = ctx
+	if err := set.Parse([]string{"--config", file}); err == nil {
+		t.Errorf("expected the config read to honor the canceled context")
+	}
+}
+
+func TestSSHConfig(t *testing.T) {
+	for _, tt := range []struct{ in, ct, path string }{
+		{"scp://deploy@web1:2222/etc/app.yaml", "yaml", "scp://deploy@web1:2222/etc/app.yaml"},
+		{"toml:scp://web1/etc/app.conf", "toml", "scp://web1/etc/app.conf"},
+		{"app.json", "json", "app.json"},
+	} {
+		if ct, path := getCotentType(tt.in); ct != tt.ct || path != tt.path {
+			t.Errorf("getCotentType(%s) = %s, %s", tt.in, ct, path)
+		}
+	}
+
+	args, err := sshArgs("scp://deploy@web1:2222/etc/it's.yaml")
+	if want := "-o BatchMode=yes -p 2222 -- deploy@web1 cat -- '/etc/it'\\''s.yaml'"; err != nil || strings.Join(args, " ") != want {
+		t.Errorf("args = %q, err = %v", args, err)
+	}
+	if args, _ = sshArgs("sftp://web1/~/app.yaml"); args[len(args)-1] != "'app.yaml'" {
+		t.Errorf("home path = %q", args)
+	}
+	if _, err = sshArgs("scp://web1"); err == nil {
+		t.Errorf("expected error without path")
+	}
+	for _, u := range []string{"scp://-oProxyCommand=id/app.yaml", "scp://-oProxyCommand=x@web1/app.yaml"} {
+		if _, err = sshArgs(u); err == nil {
+			t.Errorf("expected error for %s", u)
+		}
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	ver := Version()
+	Version("1.0.0")
+	defer func() { version = ver }()
+
+	set := pflag.NewFlagSet("errors", pflag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	ErrorHandling(pflag.ContinueOnError, set)
+
+	if err := ParseFlags(set, []string{"--version"}); err != ErrVersion {
+		t.Errorf("err = %v, want ErrVersion", err)
+	}
+
+	set = pflag.NewFlagSet("errors", pflag.ContinueOnError)
+	ErrorHandling(pflag.PanicOnError, set)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	ParseFlags(set, []string{"--unknown"})
+}
+
+func TestBlobConfig(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"s3://conf/prod/app.yaml", "aws s3 cp --only-show-errors s3://conf/prod/app.yaml -"},
+		{"gs://conf/prod/app.yaml", "gcloud storage cat gs://conf/prod/app.yaml"},
+		{"azblob://acct/conf/prod/app.yaml", "az storage blob download --auth-mode login --no-progress --output none --account-name acct --container-name conf --name prod/app.yaml --file /dev/stdout"},
+	} {
+		name, args, err := blobCommand(tt.in)
+		if got := strings.Join(append([]string{name}, args...), " "); err != nil || got != tt.want {
+			t.Errorf("blobCommand(%s) = %s, err = %v", tt.in, got, err)
+		}
+	}
+
+	for _, s := range []string{"s3://conf", "azblob://acct/conf"} {
+		if _, _, err := blobCommand(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestGitConfig(t *testing.T) {
+	repo, path, ref, err := parseGitURL("git+https://github.com/acme/conf.git//prod/app.yaml?ref=v1.2.0")
+	if err != nil || repo != "https://github.com/acme/conf.git" || path != "prod/app.yaml" || ref != "v1.2.0" {
+		t.Errorf("parseGitURL = %s, %s, %s, %v", repo, path, ref, err)
+	}
+	if _, _, _, err = parseGitURL("git+https://github.com/acme/conf.git"); err == nil {
+		t.Errorf("expected error without path")
+	}
+	if _, _, _, err = parseGitURL("git+https://github.com/acme/conf.git//app.yaml?ref=--upload-pack=touch%20/tmp/pwned"); err == nil {
+		t.Errorf("expected error for option-like ref")
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	work := filepath.Join(dir, "work")
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", work, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	os.MkdirAll(filepath.Join(work, "conf"), 0o755)
+	run("init", "-q")
+	os.WriteFile(filepath.Join(work, "conf", "app.json"), []byte(`{"port": 1}`), 0o644)
+	run("add", ".")
+	run("commit", "-q", "-m", "v1")
+	run("tag", "v1")
+	os.WriteFile(filepath.Join(work, "conf", "app.json"), []byte(`{"port": 2}`), 0o644)
+	run("commit", "-q", "-am", "v2")
+
+	var c struct{ Port int }
+	set := pflag.NewFlagSet("git", pflag.ContinueOnError)
+	BindFile(&c, "config", "", "", "配置文件", set)
+
+	src := "git+file://" + work + "//conf/app.json"
+	if err := set.Parse([]string{"--config", src + "?ref=v1"}); err != nil || c.Port != 1 {
+		t.Errorf("ref=v1: port = %d, err = %v", c.Port, err)
+	}
+	if err := set.Parse([]string{"--config", src}); err != nil || c.Port != 2 {
+		t.Errorf("HEAD: port = %d, err = %v", c.Port, err)
+	}
+
+	// 指定 ref 时使用缓存，仓库不可用也能读取
+	os.RemoveAll(work)
+	c.Port = 0
+	if err := set.Parse([]string{"--config", src + "?ref=v1"}); err != nil || c.Port != 1 {
+		t.Errorf("cached: port = %d, err = %v", c.Port, err)
+	}
+
+	if err := set.Parse([]string{"--config", src + "?ref=a..b"}); err == nil || !strings.Contains(err.Error(), "invalid git ref") {
+		t.Errorf("bad ref: err = %v", err)
+	}
+}
+
+func TestIgnoreUnknown(t *testing.T) {
+	var c struct {
+		Port    int  `flag:"p,port"`
+		Verbose bool `flag:"v,verbose"`
+	}
+
+	set := pflag.NewFlagSet("unknown", pflag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	StructBind(&c, set)
+
+	args := []string{"--port", "80", "--name", "web", "-xv", "--debug", "-y", "1", "--mode=fast", "arg"}
+	if err := ParseFlags(set, args); err == nil {
+		t.Fatalf("expected error without IgnoreUnknown")
+	}
+
+	IgnoreUnknown(set)
+	if err := ParseFlags(set, args); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 80 || !c.Verbose {
+		t.Errorf("got %+v", c)
+	}
+	if got, want := UnknownArgs(set), []string{"--name", "web", "-x", "--debug", "-y", "1", "--mode=fast"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UnknownArgs = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(set.Args(), []string{"arg"}) {
+		t.Errorf("Args = %q", set.Args())
+	}
+}
+
+func TestParsePartial(t *testing.T) {
+	var c struct {
+		Config  string `flag:"c,config"`
+		Profile string
+		Debug   bool `flag:"d,debug"`
+		Tags    []string
+	}
+
+	set := pflag.NewFlagSet("partial", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	args := []string{"--port", "80", "-dcapp.yaml", "--profile=prod", "--tags", "a", "--later", "x"}
+	if err := ParsePartial(set, args, "config", "profile", "debug", "tags"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Config != "app.yaml" || c.Profile != "prod" || !c.Debug || !reflect.DeepEqual(c.Tags, []string{"a"}) {
+		t.Errorf("got %+v", c)
+	}
+	if set.Lookup("config").Changed {
+		t.Errorf("config should not be marked as changed")
+	}
+
+	set.Parse([]string{"--tags", "b"})
+	if !reflect.DeepEqual(c.Tags, []string{"b"}) {
+		t.Errorf("tags after full parse = %v", c.Tags)
+	}
+}
+
+func TestArgsAfterDash(t *testing.T) {
+	var c struct {
+		Timeout time.Duration
+	}
+
+	set := pflag.NewFlagSet("dash", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if err := ParseFlags(set, []string{"exec", "--timeout", "5s", "--", "ls", "-la"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ArgsAfterDash(set), []string{"ls", "-la"}) || !reflect.DeepEqual(ArgsBeforeDash(set), []string{"exec"}) {
+		t.Errorf("after = %q, before = %q", ArgsAfterDash(set), ArgsBeforeDash(set))
+	}
+
+	set = pflag.NewFlagSet("dash", pflag.ContinueOnError)
+	StructBind(&c, set)
+	Interspersed(false, set)
+	if err := ParseFlags(set, []string{"--timeout", "1s", "run", "--timeout", "2s"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Timeout != time.Second || ArgsAfterDash(set) != nil || !reflect.DeepEqual(set.Args(), []string{"run", "--timeout", "2s"}) {
+		t.Errorf("timeout = %s, args = %q", c.Timeout, set.Args())
+	}
+}
+
+// 只支持固定结果的测试驱动，查询内容为 JSON 编码的 [][]string，第一行为列名
+type testSQLDriver struct{}
+
+func (testSQLDriver) Open(string) (driver.Conn, error) { return testSQLConn{}, nil }
+
+type testSQLConn struct{}
+
+func (testSQLConn) Prepare(query string) (driver.Stmt, error) { return testSQLStmt(query), nil }
+func (testSQLConn) Close() error                              { return nil }
+func (testSQLConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("not supported") }
+
+type testSQLStmt string
+
+func (s testSQLStmt) Close() error  { return nil }
+func (s testSQLStmt) NumInput() int { return 0 }
+func (s testSQLStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (s testSQLStmt) Query([]driver.Value) (driver.Rows, error) {
+	var rows [][]string
+	testSQLLock.Lock()
+	data := testSQLData[string(s)]
+	testSQLLock.Unlock()
+	if err := json.Unmarshal([]byte(data), &rows); err != nil {
+		return nil, err
+	}
+	return &testSQLRows{rows: rows}, nil
+}
+
+type testSQLRows struct{ rows [][]string }
+
+func (r *testSQLRows) Columns() []string { return r.rows[0] }
+func (r *testSQLRows) Close() error      { return nil }
+func (r *testSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) < 2 {
+		return io.EOF
+	}
+	r.rows = r.rows[1:]
+	for i, s := range r.rows[0] {
+		dest[i] = s
+	}
+	return nil
+}
+
+var (
+	testSQLData = map[string]string{}
+	testSQLLock sync.Mutex
+	testSQLOnce sync.Once
+)
+
+func TestLoadSQL(t *testing.T) {
+	testSQLOnce.Do(func() { sql.Register("flagstest", testSQLDriver{}) })
+	db, _ := sql.Open("flagstest", "")
+	defer db.Close()
+
+	testSQLData["kv"] = `[["name", "value"], ["port", "8080"], ["tags", "a,b"], ["other", "x"]]`
+	testSQLData["doc"] = `[["doc"], ["{\"db\": {\"host\": \"pg\"}, \"port\": 9090}"]]`
+	testSQLData["bad"] = `[["a", "b", "c"]]`
+
+	var c struct {
+		Port int
+		Tags []string
+		DB   struct{ Host string }
+	}
+
+	set := pflag.NewFlagSet("sql", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	ctx := context.Background()
+	if err := LoadSQL(ctx, db, "kv", set); err != nil || c.Port != 8080 || !reflect.DeepEqual(c.Tags, []string{"a", "b"}) {
+		t.Errorf("kv: %+v, err = %v", c, err)
+	}
+	if err := LoadSQL(ctx, db, "doc", set); err != nil || c.Port != 9090 || c.DB.Host != "pg" {
+		t.Errorf("doc: %+v, err = %v", c, err)
+	}
+	if err := LoadSQL(ctx, db, "bad", set); err == nil {
+		t.Errorf("expected error for 3 columns")
+	}
+
+	changed := make(chan string, 1)
+	OnSet("port", func(_, new string) { changed <- new }, set)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	WatchSQL(ctx, db, "kv", 10*time.Millisecond, func(err error) { t.Error(err) }, set)
+	if port := <-changed; port != "8080" {
+		t.Errorf("first load port = %s", port)
+	}
+
+	testSQLLock.Lock()
+	testSQLData["kv"] = `[["name", "value"], ["port", "8081"]]`
+	testSQLLock.Unlock()
+	select {
+	case port := <-changed:
+		if port != "8081" {
+			t.Errorf("reload port = %s", port)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("change not applied")
+	}
+	cancel()
+
+	// 命令行的值不会被数据库的配置覆盖
+	var cli struct {
+		Port int
+		Tags []string
+	}
+	set = pflag.NewFlagSet("sql-cli", pflag.ContinueOnError)
+	StructBind(&cli, set)
+	if err := ParseFlags(set, []string{"--port", "9090"}); err != nil {
+		t.Fatal(err)
+	}
+	testSQLLock.Lock()
+	testSQLData["kv"] = `[["name", "value"], ["port", "1"], ["tags", "a,b"]]`
+	testSQLLock.Unlock()
+	if err := LoadSQL(context.Background(), db, "kv", set); err != nil || cli.Port != 9090 || !reflect.DeepEqual(cli.Tags, []string{"a", "b"}) {
+		t.Errorf("cli: %+v, err = %v", cli, err)
+	}
+	if v := set.Lookup("port").Value.(*value); v.source != SourceFlag {
+		t.Errorf("port source = %s", v.source)
+	}
+}
+
+func TestAllowAbbrev(t *testing.T) {
+	var c struct {
+		Verbose bool
+		Version string
+		Listen  string
+		Name    string
+	}
+
+	set := pflag.NewFlagSet("abbrev", pflag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	StructBind(&c, set)
+	AllowAbbrev(set)
+
+	args := []string{"--verb", "--lis=:80", "--name", "--list", "--vers", "1.0"}
+	if err := ParseFlags(set, args); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Verbose || c.Listen != ":80" || c.Name != "--list" || c.Version != "1.0" {
+		t.Errorf("got %+v", c)
+	}
+	if args[0] != "--verb" {
+		t.Errorf("args modified: %q", args)
+	}
+
+	err := ParseFlags(set, []string{"--ver"})
+	if err == nil || !strings.Contains(err.Error(), "possibilities: --verbose --version") {
+		t.Errorf("ambiguous: %v", err)
+	}
+}
+
+func TestSuggestFlags(t *testing.T) {
+	var c struct {
+		Listen string
+		Level  string
+		Secret string `hidden:"true"`
+	}
+
+	set := pflag.NewFlagSet("suggest", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	for _, tt := range []struct{ arg, want string }{
+		{"--listn", "unknown flag: --listn, did you mean --listen?"},
+		{"--levle", "unknown flag: --levle, did you mean --level?"},
+		{"--secre", "unknown flag: --secre"},
+		{"--zzzzzz", "unknown flag: --zzzzzz"},
+	} {
+		if err := ParseFlags(set, []string{tt.arg}); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: %v", tt.arg, err)
+		}
+	}
+
+	stderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+	ParseFlags(set, []string{"--listn"})
+	w.Close()
+	os.Stderr = stderr
+	out, _ := io.ReadAll(r)
+
+	if !strings.Contains(string(out), "--listen") || !strings.HasSuffix(string(out), "did you mean --listen?\n") {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	// 模拟 ConfigMap 挂载: app.json -> ..data/app.json, ..data -> ..v1
+	version := func(v, content string) {
+		os.MkdirAll(filepath.Join(dir, v), 0o755)
+		os.WriteFile(filepath.Join(dir, v, "app.json"), []byte(content), 0o644)
+		os.Symlink(v, filepath.Join(dir, "..data_tmp"))
+		os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data"))
+	}
+	version("..v1", `{"level": "info", "port": 80}`)
+	os.Symlink(filepath.Join("..data", "app.json"), filepath.Join(dir, "app.json"))
+
+	var c struct {
+		Level string
+		Port  int
+	}
+
+	set := pflag.NewFlagSet("watch", pflag.ContinueOnError)
+	StructBind(&c, set)
+	BindFile(&c, "config", "", "", "配置文件", set)
+	if err := ParseFlags(set, []string{"--config", filepath.Join(dir, "app.json"), "--port", "8080"}); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan string, 1)
+	OnSet("level", func(_, new string) { changed <- new }, set)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchConfig(ctx, "config", 10*time.Millisecond, func(err error) { t.Error(err) }, set); err != nil {
+		t.Fatal(err)
+	}
+
+	version("..v2", `{"level": "debug", "port": 81}`)
+	select {
+	case level := <-changed:
+		if level != "debug" {
+			t.Errorf("level = %s", level)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("change not applied")
+	}
+	cancel()
+
+	if c.Port != 8080 {
+		t.Errorf("command line port overwritten: %d", c.Port)
+	}
+	if err := WatchConfig(ctx, "port", time.Second, nil, set); err == nil {
+		t.Errorf("expected error for non config flag")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	os.WriteFile(path, []byte(`{"level": "info"}`), 0o644)
+
+	var c struct{ Level string }
+	set := New("refresh")
+	set.Struct(&c)
+	BindFile(&c, "config", "", "", "配置文件", set.FlagSet)
+	if err := set.ParseArgs([]string{"--config", path}); err != nil {
+		t.Fatal(err)
+	}
+	defer set.Release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, p := range []Poll{{}, {Interval: time.Second, Jitter: -1}, {Interval: time.Second, MaxSkew: -1}} {
+		if err := WatchConfigPoll(ctx, "config", p, nil, set.FlagSet); err == nil {
+			t.Errorf("expected error for %+v", p)
+		}
+	}
+	if err := WatchConfigPoll(ctx, "config", Poll{Interval: time.Hour, Jitter: time.Minute, MaxSkew: time.Minute}, func(err error) { t.Error(err) }, set.FlagSet); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(path, []byte(`{"level": "debug"}`), 0o644)
+	if err := set.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != "debug" {
+		t.Errorf("level = %s", c.Level)
+	}
+
+	os.WriteFile(path, []byte(`{"level": `), 0o644)
+	if err := set.Refresh(ctx); err == nil {
+		t.Errorf("expected error for invalid config")
+	}
+
+	// watch 停止后不再读取
+	cancel()
+	os.WriteFile(path, []byte(`{"level": "warn"}`), 0o644)
+	if err := set.Refresh(context.Background()); err != nil || c.Level != "debug" {
+		t.Errorf("refresh after cancel: level = %s, err = %v", c.Level, err)
+	}
+}
+
+func TestWatchConfigBlob(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	// 用脚本代替 aws 命令行，输出本地文件的内容
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "app.json")
+	os.WriteFile(conf, []byte(`{"level": "info"}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "aws"), []byte("#!/bin/sh\ncat "+conf+"\n"), 0o755)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var c struct{ Level string }
+	set := pflag.NewFlagSet("blob", pflag.ContinueOnError)
+	StructBind(&c, set)
+	BindFile(&c, "config", "", "", "配置文件", set)
+	if err := ParseFlags(set, []string{"--config", "s3://conf/prod/app.json"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != "info" {
+		t.Fatalf("level = %s", c.Level)
+	}
+
+	changed := make(chan string, 1)
+	OnSet("level", func(_, new string) { changed <- new }, set)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchConfig(ctx, "config", 10*time.Millisecond, func(err error) { t.Error(err) }, set); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(conf+".tmp", []byte(`{"level": "debug"}`), 0o644)
+	os.Rename(conf+".tmp", conf)
+	select {
+	case level := <-changed:
+		if level != "debug" {
+			t.Errorf("level = %s", level)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("change not applied")
+	}
+}
+
+// 模拟 Redis 服务，只实现读取配置和订阅用到的命令
+func fakeRedis(t *testing.T, password string, keys map[string]any) (addr string, mu *sync.Mutex, publish func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	mu = &sync.Mutex{}
+	var subs []net.Conn
+	bulk := func(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+	serve := func(c net.Conn) {
+		defer c.Close()
+		rc := &redisConn{conn: c, r: bufio.NewReader(c)}
+		for {
+			reply, err := rc.read()
+			if err != nil {
+				return
+			}
+			var args []string
+			for _, it := range reply.([]any) {
+				args = append(args, it.(string))
+			}
+
+			mu.Lock()
+			switch v := keys[args[len(args)-1]]; args[0] {
+			case "AUTH":
+				if args[len(args)-1] == password {
+					io.WriteString(c, "+OK\r\n")
+				} else {
+					io.WriteString(c, "-WRONGPASS invalid password\r\n")
+				}
+			case "SELECT":
+				io.WriteString(c, "+OK\r\n")
+			case "TYPE":
+				switch v.(type) {
+				case map[string]string:
+					io.WriteString(c, "+hash\r\n")
+				case string:
+					io.WriteString(c, "+string\r\n")
+				default:
+					io.WriteString(c, "+none\r\n")
+				}
+			case "GET":
+				io.WriteString(c, bulk(v.(string)))
+			case "HGETALL":
+				h := v.(map[string]string)
+				fmt.Fprintf(c, "*%d\r\n", len(h)*2)
+				for k, s := range h {
+					io.WriteString(c, bulk(k)+bulk(s))
+				}
+			case "SUBSCRIBE":
+				io.WriteString(c, "*3\r\n"+bulk("subscribe")+bulk(args[1])+":1\r\n")
+				subs = append(subs, c)
+			}
+			mu.Unlock()
+		}
+	}
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(c)
+		}
+	}()
+
+	publish = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range subs {
+			io.WriteString(c, "*3\r\n"+bulk("message")+bulk("app:changed")+bulk("x"))
+		}
+	}
+	return "redis://:" + password + "@" + ln.Addr().String() + "/1", mu, publish
+}
+
+func TestRedisReplyLimit(t *testing.T) {
+	for _, reply := range []string{"$1073741824\r\n", "*1073741824\r\n", "$3\r\nabc\r\n"} {
+		c := &redisConn{r: bufio.NewReader(strings.NewReader(reply))}
+		v, err := c.read()
+		if reply[1] == '3' {
+			if err != nil || v != "abc" {
+				t.Errorf("%q: %v, %v", reply, v, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), "exceeds") {
+			t.Errorf("%q: err = %v", reply, err)
+		}
+	}
+}
+
+func TestWatchRedis(t *testing.T) {
+	app := map[string]string{"level": "info", "port": "80", "region": "r1", "other": "x"}
+	addr, mu, publish := fakeRedis(t, "secret", map[string]any{"app": app, "doc": `{"level": "warn", "http": {"port": 81}}`})
+
+	var c struct {
+		Level  string
+		Port   int
+		Region string
+		HTTP   struct{ Port int }
+	}
+	set := pflag.NewFlagSet("redis", pflag.ContinueOnError)
+	StructBind(&c, set)
+	if err := ParseFlags(set, []string{"--region", "cli"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := LoadRedis(ctx, addr, "doc", set); err != nil || c.Level != "warn" || c.HTTP.Port != 81 {
+		t.Fatalf("LoadRedis: %+v, err = %v", c, err)
+	}
+	if err := LoadRedis(ctx, strings.Replace(addr, "secret", "wrong", 1), "doc", set); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("wrong password: %v", err)
+	}
+	if err := LoadRedis(ctx, addr, "missing", set); err == nil {
+		t.Errorf("expected error for missing key")
+	}
+
+	reloads, errs := make(chan map[string]string, 4), make(chan error, 4)
+	OnReload(func(_ context.Context, changed map[string]string) error { reloads <- changed; return nil }, set)
+	if err := WatchRedis(ctx, addr, "app", "app:changed", func(err error) { errs <- err }, set); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != "info" || c.Port != 80 {
+		t.Fatalf("got %+v", c)
+	}
+
+	wait := func() {
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			publish()
+			select {
+			case changed := <-reloads:
+				if len(changed) != 1 || changed["level"] != "debug" {
+					t.Errorf("changed = %v", changed)
+				}
+				return
+			case err := <-errs:
+				if !strings.Contains(err.Error(), "port") {
+					t.Errorf("err = %v", err)
+				}
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+		t.Fatalf("no reload")
+	}
+
+	mu.Lock()
+	app["level"] = "debug"
+	mu.Unlock()
+	wait()
+	if c.Level != "debug" {
+		t.Errorf("level = %s", c.Level)
+	}
+
+	// 校验失败时全部不写入
+	mu.Lock()
+	app["level"], app["port"] = "error", "bad"
+	mu.Unlock()
+	wait()
+	if c.Level != "debug" || c.Port != 80 {
+		t.Errorf("invalid config applied: %+v", c)
+	}
+	if c.Region != "cli" {
+		t.Errorf("command line region overwritten: %s", c.Region)
+	}
+}
+
+// 模拟开启了 JetStream 的 NATS 服务，只实现读取 KV 和订阅用到的协议，
+// bucket 不是 app 时按没有 JetStream 回复 503
+func fakeNATS(t *testing.T, token string, kv map[string]string) (addr string, mu *sync.Mutex, publish func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	mu = &sync.Mutex{}
+	var watchers []net.Conn
+
+	serve := func(c net.Conn) {
+		defer c.Close()
+		r := bufio.NewReader(c)
+		io.WriteString(c, `INFO {"server_id":"fake","headers":true}`+"\r\n")
+		subs := map[string]string{}
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+
+			mu.Lock()
+			switch fields[0] {
+			case "CONNECT":
+				var opts struct {
+					Token string `json:"auth_token"`
+				}
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &opts)
+				if opts.Token != token {
+					io.WriteString(c, "-ERR 'Authorization Violation'\r\n")
+					mu.Unlock()
+					return
+				}
+			case "PING":
+				io.WriteString(c, "PONG\r\n")
+			case "SUB":
+				subs[fields[1]] = fields[2]
+				if fields[1] == "$KV.app.>" {
+					watchers = append(watchers, c)
+				}
+			case "PUB":
+				n, _ := strconv.Atoi(fields[3])
+				payload := make([]byte, n+2)
+				io.ReadFull(r, payload)
+				if fields[1] != "$JS.API.STREAM.MSG.GET.KV_app" {
+					fmt.Fprintf(c, "HMSG %s %s 16 16\r\nNATS/1.0 503\r\n\r\n\r\n", fields[2], subs[fields[2]])
+					break
+				}
+				var req struct {
+					Subject string `json:"last_by_subj"`
+				}
+				json.Unmarshal(payload[:n], &req)
+				resp := `{"error":{"code":404,"err_code":10037,"description":"no message found"}}`
+				if v, ok := kv[strings.TrimPrefix(req.Subject, "$KV.app.")]; ok {
+					b, _ := json.Marshal(map[string]any{"message": map[string]any{"subject": req.Subject, "data": []byte(v)}})
+					resp = string(b)
+				}
+				fmt.Fprintf(c, "MSG %s %s %d\r\n%s\r\n", fields[2], subs[fields[2]], len(resp), resp)
+			}
+			mu.Unlock()
+		}
+	}
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(c)
+		}
+	}()
+
+	publish = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range watchers {
+			io.WriteString(c, "MSG $KV.app.level 1 1\r\nx\r\n")
+		}
+	}
+	return "nats://" + token + "@" + ln.Addr().String(), mu, publish
+}
+
+func TestWatchNATS(t *testing.T) {
+	kv := map[string]string{"level": "info", "port": "80", "region": "r1", "other": "x"}
+	addr, mu, publish := fakeNATS(t, "secret", kv)
+
+	var c struct {
+		Level  string
+		Port   int
+		Region string
+	}
+	set := pflag.NewFlagSet("nats", pflag.ContinueOnError)
+	StructBind(&c, set)
+	if err := ParseFlags(set, []string{"--region", "cli"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := LoadNATS(ctx, strings.Replace(addr, "secret", "wrong", 1), "app", set); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("wrong token: %v", err)
+	}
+	if err := LoadNATS(ctx, addr, "other", set); err == nil || !strings.Contains(err.Error(), "no responders") {
+		t.Errorf("missing bucket: %v", err)
+	}
+	if err := LoadNATS(ctx, addr, "a.b", set); err == nil {
+		t.Errorf("expected error for invalid bucket")
+	}
+
+	reloads, errs := make(chan map[string]string, 4), make(chan error, 4)
+	OnReload(func(_ context.Context, changed map[string]string) error { reloads <- changed; return nil }, set)
+	if err := WatchNATS(ctx, addr, "app", func(err error) { errs <- err }, set); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != "info" || c.Port != 80 || c.Region != "cli" {
+		t.Fatalf("got %+v", c)
+	}
+
+	wait := func() {
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			publish()
+			select {
+			case changed := <-reloads:
+				if len(changed) != 1 || changed["level"] != "debug" {
+					t.Errorf("changed = %v", changed)
+				}
+				return
+			case err := <-errs:
+				if !strings.Contains(err.Error(), "port") {
+					t.Errorf("err = %v", err)
+				}
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+		t.Fatalf("no reload")
+	}
+
+	mu.Lock()
+	kv["level"] = "debug"
+	mu.Unlock()
+	wait()
+	if c.Level != "debug" {
+		t.Errorf("level = %s", c.Level)
+	}
+
+	// 校验失败时全部不写入
+	mu.Lock()
+	kv["level"], kv["port"] = "error", "bad"
+	mu.Unlock()
+	wait()
+	if c.Level != "debug" || c.Port != 80 || c.Region != "cli" {
+		t.Errorf("invalid config applied: %+v", c)
+	}
+}
+
+func TestNATSHeader(t *testing.T) {
+	hdr := []byte("NATS/1.0\r\nKV-Operation: DEL\r\n\r\n")
+	if op := natsHeader(hdr, "kv-operation"); op != "DEL" {
+		t.Errorf("op = %q", op)
+	}
+	if s := natsStatus([]byte("NATS/1.0 503\r\n\r\n")); s != "503" {
+		t.Errorf("status = %q", s)
+	}
+	c := &natsConn{r: bufio.NewReader(strings.NewReader("MSG a 1 1073741824\r\n"))}
+	if _, err := c.read(); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 模拟 MQTT 服务，只实现读取保留消息和订阅用到的报文，tlsConfig 不为 nil 时使用 TLS
+func fakeMQTT(t *testing.T, password string, tlsConfig *tls.Config) (addr string, publish func(doc string)) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	var subs []*mqttConn
+	retained := ""
+	send := func(c *mqttConn, doc string, retain bool) {
+		var typ byte = 0x30
+		if retain {
+			typ |= 1
+		}
+		c.write(typ, append(mqttString("app/config"), doc...))
+	}
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		c := &mqttConn{conn: conn, r: bufio.NewReader(conn)}
+		for {
+			typ, body, err := c.read()
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			switch typ {
+			case 0x10:
+				// 协议名、版本、标志、keep alive 之后依次为 client id、用户名、密码
+				var fields []string
+				for i := 10; i+2 <= len(body); {
+					n := int(body[i])<<8 | int(body[i+1])
+					fields = append(fields, string(body[i+2:i+2+n]))
+					i += 2 + n
+				}
+				if len(fields) != 3 || fields[2] != password {
+					c.write(0x20, []byte{0, 4})
+				} else {
+					c.write(0x20, []byte{0, 0})
+				}
+			case 0x82:
+				c.write(0x90, []byte{body[0], body[1], 0})
+				if retained != "" {
+					send(c, retained, true)
+				}
+				subs = append(subs, c)
+			}
+			mu.Unlock()
+		}
+	}
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(c)
+		}
+	}()
+
+	publish = func(doc string) {
+		mu.Lock()
+		defer mu.Unlock()
+		retained = doc
+		for _, c := range subs {
+			send(c, doc, false)
+		}
+	}
+	return "mqtt://app:" + password + "@" + ln.Addr().String(), publish
+}
+
+func TestWatchMQTT(t *testing.T) {
+	addr, publish := fakeMQTT(t, "secret", nil)
+
+	var c struct {
+		Level  string
+		Port   int
+		Region string
+		HTTP   struct{ Port int }
+	}
+	set := pflag.NewFlagSet("mqtt", pflag.ContinueOnError)
+	StructBind(&c, set)
+	if err := ParseFlags(set, []string{"--region", "cli"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	defer func(d time.Duration) { MQTTWaitTimeout = d }(MQTTWaitTimeout)
+	MQTTWaitTimeout = 50 * time.Millisecond
+	if err := LoadMQTT(ctx, addr, "app/config", nil, set); err == nil || !strings.Contains(err.Error(), "no retained message") {
+		t.Errorf("no retained message: %v", err)
+	}
+
+	publish(`{"level": "info", "port": 80, "region": "r1", "http": {"port": 81}}`)
+	if err := LoadMQTT(ctx, strings.Replace(addr, "secret", "wrong", 1), "app/config", nil, set); err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Errorf("wrong password: %v", err)
+	}
+	if err := LoadMQTT(ctx, addr, "app/#", nil, set); err == nil {
+		t.Errorf("expected error for wildcard topic")
+	}
+
+	reloads, errs := make(chan map[string]string, 4), make(chan error, 4)
+	OnReload(func(_ context.Context, changed map[string]string) error { reloads <- changed; return nil }, set)
+	if err := WatchMQTT(ctx, addr, "app/config", nil, func(err error) { errs <- err }, set); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != "info" || c.Port != 80 || c.HTTP.Port != 81 || c.Region != "cli" {
+		t.Fatalf("got %+v", c)
+	}
+
+	wait := func(doc string) {
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			publish(doc)
+			select {
+			case changed := <-reloads:
+				if len(changed) != 1 || changed["level"] != "debug" {
+					t.Errorf("changed = %v", changed)
+				}
+				return
+			case err := <-errs:
+				if !strings.Contains(err.Error(), "port") {
+					t.Errorf("err = %v", err)
+				}
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+		t.Fatalf("no reload")
+	}
+
+	// YAML 文档
+	wait("level: debug\nport: 80\nhttp:\n  port: 81\n")
+	if c.Level != "debug" {
+		t.Errorf("level = %s", c.Level)
+	}
+
+	// 校验失败时全部不写入
+	wait(`{"level": "error", "port": "bad"}`)
+	if c.Level != "debug" || c.Port != 80 || c.Region != "cli" {
+		t.Errorf("invalid config applied: %+v", c)
+	}
+}
+
+func TestLoadMQTTTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	cert, pool := srv.TLS.Certificates[0], x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	srv.Close()
+
+	addr, publish := fakeMQTT(t, "secret", &tls.Config{Certificates: []tls.Certificate{cert}})
+	publish("level: warn\n")
+	addr = strings.Replace(addr, "mqtt://", "mqtts://", 1)
+
+	var c struct{ Level string }
+	set := pflag.NewFlagSet("mqtts", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	ctx := context.Background()
+	if err := LoadMQTT(ctx, addr, "app/config", nil, set); err == nil {
+		t.Errorf("expected error for unknown certificate authority")
+	}
+	if err := LoadMQTT(ctx, addr, "app/config", &tls.Config{RootCAs: pool}, set); err != nil || c.Level != "warn" {
+		t.Errorf("level = %s, err = %v", c.Level, err)
+	}
+}
+
+func TestReloadCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	dir := t.TempDir()
+	conf, out := filepath.Join(dir, "app.json"), filepath.Join(dir, "out")
+	script := filepath.Join(dir, "reload.sh")
+	os.WriteFile(conf, []byte(`{"level": "info", "token": "a"}`), 0o644)
+	os.WriteFile(script, []byte("#!/bin/sh\necho \"$FLAGS_CHANGED\" > "+out+"\ncat >> "+out+"\n"), 0o755)
+
+	var c struct {
+		Level string
+		Token string `secret:"true"`
+	}
+
+	set := pflag.NewFlagSet("reload", pflag.ContinueOnError)
+	StructBind(&c, set)
+	BindFile(&c, "config", "", "", "配置文件", set)
+	ReloadCommandFlag(set)
+	if err := ParseFlags(set, []string{"--config", conf, "--on-reload", script}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan map[string]string, 1)
+	OnReload(func(_ context.Context, changed map[string]string) error { done <- changed; return nil }, set)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchConfig(ctx, "config", 10*time.Millisecond, func(err error) { t.Error(err) }, set); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(conf+".tmp", []byte(`{"level": "debug", "token": "b"}`), 0o644)
+	os.Rename(conf+".tmp", conf)
+	select {
+	case changed := <-done:
+		if len(changed) != 2 || changed["level"] != "debug" || changed["token"] != "b" {
+			t.Errorf("changed = %v", changed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("reload hook not called")
+	}
+	cancel()
+
+	data, _ := os.ReadFile(out)
+	if want := "level,token\n{\"level\":\"debug\",\"token\":\"******\"}"; string(data) != want {
+		t.Errorf("command output = %q, want %q", data, want)
+	}
+}
+
+func TestDownward(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "namespace"), []byte("prod\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "labels"), []byte("app=\"web\"\ntier=\"frontend\"\n"), 0o644)
+
+	Init("downward", map[string]string{"POD_NAMESPACE": "staging"})
+	defer Init("", nil)
+
+	var c struct {
+		Namespace string            `downward:"namespace"`
+		Labels    map[string]string `downward:"labels"`
+		App       string            `downward:"labels/app"`
+		Node      string            `downward:"nodename"`
+		EnvFirst  string            `downward:"namespace" env:"POD_NAMESPACE"`
+	}
+
+	set := pflag.NewFlagSet("downward", pflag.ContinueOnError)
+	DownwardDir(dir, set)
+	StructBind(&c, set)
+
+	if c.Namespace != "prod" || c.App != "web" || c.Node != "" || c.EnvFirst != "staging" {
+		t.Errorf("got %+v", c)
+	}
+	if !reflect.DeepEqual(c.Labels, map[string]string{"app": "web", "tier": "frontend"}) {
+		t.Errorf("labels = %v", c.Labels)
+	}
+	if v := set.Lookup("namespace").Value.(*value); v.source != SourceMetadata {
+		t.Errorf("source = %s", v.source)
+	}
+}
+
+func TestFlagSource(t *testing.T) {
+	file := t.TempDir() + "/app.json"
+	os.WriteFile(file, []byte(`{"host": "db"}`), 0o644)
+
+	Init("sources", map[string]string{"APP_PORT": "9090"})
+	defer Init("", nil)
+
+	var c struct {
+		Port  int `env:"APP_PORT"`
+		Host  string
+		Level string
+		Debug bool
+	}
+
+	set := pflag.NewFlagSet("sources", pflag.ContinueOnError)
+	StructBind(&c, set)
+	BindFile(&c, "config", "", "", "配置文件", set)
+	if err := ParseFlags(set, []string{"--config", file, "--debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"port": "env APP_PORT", "host": "config " + file, "level": "default ", "debug": "flag "} {
+		if src, key := FlagSource(name, set); src.String()+" "+key != want {
+			t.Errorf("%s: %s %s, want %s", name, src, key, want)
+		}
+	}
+
+	var buf strings.Builder
+	PrintSources(&buf, set)
+	if !strings.Contains(buf.String(), "--port = 9090 (env: APP_PORT)\n") || !strings.Contains(buf.String(), "--host = db (config: "+file+")\n") {
+		t.Errorf("PrintSources:\n%s", buf.String())
+	}
+}
+
+func TestCloudMetadata(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			io.WriteString(w, "token")
+		case r.Header.Get("X-aws-ec2-metadata-token") != "token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/latest/meta-data/placement/region":
+			io.WriteString(w, "us-east-1")
+		case r.URL.Path == "/latest/meta-data/instance-id":
+			io.WriteString(w, "i-0abc")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	old := awsMetadataURL
+	awsMetadataURL = srv.URL
+	defer func() { awsMetadataURL = old }()
+
+	var c struct {
+		Region   string `metadata:"region"`
+		Instance string `metadata:"instance-id"`
+		Zone     string `metadata:"zone"`
+	}
+
+	for i := 0; i < 2; i++ {
+		set := pflag.NewFlagSet("metadata", pflag.ContinueOnError)
+		StructBind(&c, set)
+		CloudMetadata("aws", set)
+		if err := ParseFlags(set, []string{"--instance", "i-local"}); err != nil {
+			t.Fatal(err)
+		}
+		if c.Region != "us-east-1" || c.Instance != "i-local" || c.Zone != "" {
+			t.Errorf("got %+v", c)
+		}
+		if src, key := FlagSource("region", set); src != SourceMetadata || key != "aws:region" {
+			t.Errorf("source = %s %s", src, key)
+		}
+	}
+
+	// region 第二次使用缓存，zone 读取失败不缓存，每次 2 个请求
+	if requests != 6 {
+		t.Errorf("requests = %d", requests)
+	}
+}
+
+func TestCloudMetadataUnavailable(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	urls := []*string{&awsMetadataURL, &gcpMetadataURL, &azureMetadataURL}
+	for _, u := range urls {
+		u, old := u, *u
+		*u = srv.URL
+		defer func() { *u = old }()
+	}
+
+	var c struct {
+		Region   string `metadata:"region"`
+		Zone     string `metadata:"zone"`
+		Instance string `metadata:"instance-type"`
+	}
+	set := pflag.NewFlagSet("metadata", pflag.ContinueOnError)
+	StructBind(&c, set)
+	CloudMetadata("auto", set)
+	if err := ParseFlags(set, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每个云服务商只检测一次，和字段的个数无关
+	if requests != 3 {
+		t.Errorf("requests = %d", requests)
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	c := struct {
+		Port     int
+		Password string `secret:"true"`
+		Timeout  time.Duration
+		Tags     []string
+		DB       struct{ Host string }
+	}{Port: 80, Password: "p@ss", Timeout: time.Minute, Tags: []string{"a", "b"}}
+	c.DB.Host = "pg"
+
+	set := pflag.NewFlagSet("print", pflag.ContinueOnError)
+	StructBind(&c, set)
+	PrintConfigFlag(true, set)
+	ErrorHandling(pflag.ContinueOnError, set)
+
+	if f := set.Lookup("print-config"); !f.Hidden {
+		t.Errorf("print-config should be hidden")
+	}
+
+	var buf strings.Builder
+	if err := WriteConfig(&buf, "json", set); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	json.Unmarshal([]byte(buf.String()), &got)
+	want := map[string]any{"port": float64(80), "password": "******", "timeout": "1m", "tags": []any{"a", "b"}, "db": map[string]any{"host": "pg"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("json = %s", buf.String())
+	}
+
+	for _, format := range []string{"yaml", "toml"} {
+		buf.Reset()
+		if err := WriteConfig(&buf, format, set); err != nil || !strings.Contains(buf.String(), "******") || strings.Contains(buf.String(), "p@ss") {
+			t.Errorf("%s = %s, err = %v", format, buf.String(), err)
+		}
+	}
+
+	stdout := os.Stdout
+	os.Stdout, _ = os.Create(os.DevNull)
+	err := ParseFlags(set, []string{"--print-config=yaml"})
+	os.Stdout.Close()
+	os.Stdout = stdout
+	if err != ErrPrintConfig {
+		t.Errorf("err = %v, want ErrPrintConfig", err)
+	}
+}
+
+func TestCgroupDefaults(t *testing.T) {
+	root := cgroupRoot
+	defer func() { cgroupRoot = root }()
+
+	cgroupRoot = t.TempDir()
+	os.WriteFile(filepath.Join(cgroupRoot, "cpu.max"), []byte("150000 100000\n"), 0o644)
+	os.WriteFile(filepath.Join(cgroupRoot, "memory.max"), []byte("1073741824\n"), 0o644)
+
+	RegisterDefault("test.name", func(arg string) (string, error) { return "svc-" + arg, nil })
+
+	var c struct {
+		Workers int    `defaultfrom:"cgroup.cpus"`
+		Pool    int    `defaultfrom:"cgroup.cpus:4"`
+		Cache   int64  `defaultfrom:"cgroup.memory:50%"`
+		Name    string `defaultfrom:"test.name:api"`
+	}
+	set := pflag.NewFlagSet("cgroup", pflag.ContinueOnError)
+	StructBind(&c, set)
+	if c.Workers != 2 || c.Pool != 8 || c.Cache != 512<<20 || c.Name != "svc-api" {
+		t.Errorf("got %+v", c)
+	}
+	if src, key := FlagSource("workers", set); src != SourceDefault || key != "cgroup.cpus" {
+		t.Errorf("source = %s %s", src, key)
+	}
+
+	// cgroup v1，内存未限制
+	cgroupRoot = t.TempDir()
+	os.MkdirAll(filepath.Join(cgroupRoot, "cpu"), 0o755)
+	os.MkdirAll(filepath.Join(cgroupRoot, "memory"), 0o755)
+	os.WriteFile(filepath.Join(cgroupRoot, "cpu/cpu.cfs_quota_us"), []byte("50000"), 0o644)
+	os.WriteFile(filepath.Join(cgroupRoot, "cpu/cpu.cfs_period_us"), []byte("100000"), 0o644)
+	os.WriteFile(filepath.Join(cgroupRoot, "memory/memory.limit_in_bytes"), []byte("9223372036854771712"), 0o644)
+
+	if cpus, ok := CgroupCPUs(); !ok || cpus != 0.5 {
+		t.Errorf("cpus = %v %v", cpus, ok)
+	}
+	if _, ok := CgroupMemory(); ok {
+		t.Errorf("memory should be unlimited")
+	}
+
+	c.Cache = 64
+	set = pflag.NewFlagSet("cgroup", pflag.ContinueOnError)
+	StructBind(&c, set)
+	if c.Workers != 1 || c.Cache != 64 {
+		t.Errorf("got %+v", c)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	c := struct {
+		Password string `secret:"true"`
+		Token    string
+		Empty    string `secret:"true"`
+	}{Password: "p@ss", Token: "t0ken"}
+
+	set := pflag.NewFlagSet("secret", pflag.ContinueOnError)
+	StructBind(&c, set)
+	apiKey := set.String("api-key", "k3y", "API key")
+	if err := MarkSecret(set, "token", "api-key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := MarkSecret(set, "missing"); err == nil {
+		t.Errorf("want error for undefined flag")
+	}
+
+	var out strings.Builder
+	out.WriteString(RenderHelp(set))
+	PrintSources(&out, set)
+	GenMarkdown(&out, set)
+	WriteConfig(&out, "json", set)
+
+	for _, s := range []string{"p@ss", "t0ken", "k3y"} {
+		if strings.Contains(out.String(), s) {
+			t.Errorf("%s leaked:\n%s", s, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "******") {
+		t.Errorf("no redacted value:\n%s", out.String())
+	}
+	if f := set.Lookup("api-key"); f.DefValue != "k3y" || *apiKey != "k3y" {
+		t.Errorf("default changed: %s", f.DefValue)
+	}
+
+	// StructPrint 不关联 FlagSet，只识别标签
+	out.Reset()
+	StructPrint(&c, func(s string) { out.WriteString(s + "\n") })
+	if strings.Contains(out.String(), "p@ss") || !strings.Contains(out.String(), "t0ken") {
+		t.Errorf("StructPrint:\n%s", out.String())
+	}
+}
+
+func TestNetDefaults(t *testing.T) {
+	var c struct {
+		Node string     `defaultFrom:"hostname"`
+		FQDN string     `defaultfrom:"fqdn"`
+		IP   netip.Addr `defaultfrom:"outbound-ip:127.0.0.1:9"`
+		Port int        `defaultFrom:"freeport"`
+	}
+	set := pflag.NewFlagSet("net", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	host, _ := os.Hostname()
+	if c.Node != host || c.FQDN == "" || c.IP.String() != "127.0.0.1" || c.Port <= 0 {
+		t.Errorf("got %+v", c)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	in, out, terminal, echo := promptIn, promptOut, stdinIsTerminal, setEcho
+	defer func() { promptIn, promptOut, stdinIsTerminal, setEcho = in, out, terminal, echo }()
+
+	t.Setenv("CI", "")
+	var echoes []bool
+	var buf strings.Builder
+	promptIn, promptOut = strings.NewReader("s3cret\nadmin\n"), &buf
+	stdinIsTerminal = func() bool { return true }
+	setEcho = func(on bool) { echoes = append(echoes, on) }
+
+	var c struct {
+		Password string `secret:"true" prompt:"Enter password:"`
+		User     string `prompt:"User:"`
+		Host     string `prompt:"Host:"`
+	}
+	c.Host = "localhost"
+	set := pflag.NewFlagSet("prompt", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	if err := ParseFlags(set, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.Password != "s3cret" || c.User != "admin" || c.Host != "localhost" {
+		t.Errorf("got %+v", c)
+	}
+	if buf.String() != "Enter password: \nUser: " || !reflect.DeepEqual(echoes, []bool{false, true}) {
+		t.Errorf("output = %q, echoes = %v", buf.String(), echoes)
+	}
+	if src, _ := FlagSource("user", set); src != SourcePrompt {
+		t.Errorf("source = %s", src)
+	}
+
+	// CI 中不提示
+	t.Setenv("CI", "true")
+	c.User = ""
+	buf.Reset()
+	set = pflag.NewFlagSet("prompt", pflag.ContinueOnError)
+	StructBind(&c, set)
+	set.Set("password", "x")
+	if err := ParseFlags(set, nil); err != nil || c.User != "" || buf.Len() > 0 {
+		t.Errorf("user = %q, output = %q, err = %v", c.User, buf.String(), err)
+	}
+
+	// ctx 取消时停止等待
+	t.Setenv("CI", "")
+	r, w := io.Pipe()
+	defer w.Close()
+	promptIn = r
+	set = pflag.NewFlagSet("prompt", pflag.ContinueOnError)
+	StructBind(&c, set)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := ParseFlagsContext(ctx, set, nil); err != context.DeadlineExceeded {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestBindJSON(t *testing.T) {
+	t.Setenv("APP_CONFIG_JSON", `{"port": 9090, "host": "db", "db": {"user": "root"}}`)
+
+	var c struct {
+		Port int
+		Host string
+		Name string
+		DB   struct{ User string }
+	}
+	set := pflag.NewFlagSet("inline", pflag.ContinueOnError)
+	StructBind(&c, set)
+	BindJSON(&c, "config-json", "APP_CONFIG_JSON", "inline config", set)
+
+	if c.Port != 9090 || c.Host != "db" || c.DB.User != "root" {
+		t.Fatalf("env: %+v", c)
+	}
+	if src, key := FlagSource("port", set); src != SourceConfig || key != "APP_CONFIG_JSON" {
+		t.Errorf("source = %s %s", src, key)
+	}
+
+	if err := ParseFlags(set, []string{"--config-json", `{"port": 8080, "name": "api"}`, "--host", "cache"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 8080 || c.Name != "api" || c.Host != "cache" || c.DB.User != "root" {
+		t.Errorf("cli: %+v", c)
+	}
+	if src, key := FlagSource("name", set); src != SourceConfig || key != "--config-json" {
+		t.Errorf("source = %s %s", src, key)
+	}
+
+	if err := ParseFlags(set, []string{"--config-json", `{"port": "x"}`}); err == nil {
+		t.Errorf("want error for invalid json")
+	}
+}
+
+func TestSecretResolver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "tk" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			fmt.Fprint(w, `{"data": {"data": {"password": "p@ss", "port": 5432}, "metadata": {"version": 1}}}`)
+		case "/v1/kv/token":
+			fmt.Fprint(w, `{"data": {"value": "t0ken"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	defer func() { secretResolvers = nil }()
+	RegisterSecretResolver("vault", &VaultResolver{Addr: srv.URL, Token: "tk"})
+
+	file := filepath.Join(t.TempDir(), "app.json")
+	os.WriteFile(file, []byte(`{"token": "vault:kv/token"}`), 0o644)
+
+	var c struct {
+		Password string
+		Port     int
+		Token    string
+		Plain    string
+	}
+	set := pflag.NewFlagSet("vault", pflag.ContinueOnError)
+	StructBind(&c, set)
+	BindFile(&c, "config", "", "", "", set)
+
+	err := ParseFlags(set, []string{"--config", file, "--password", "vault:secret/data/app#password", "--port=vault:secret/data/app#port", "--plain", "novault:x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Password != "p@ss" || c.Port != 5432 || c.Token != "t0ken" || c.Plain != "novault:x" {
+		t.Errorf("got %+v", c)
+	}
+
+	var out strings.Builder
+	PrintSources(&out, set)
+	WriteConfig(&out, "json", set)
+	if strings.Contains(out.String(), "p@ss") || strings.Contains(out.String(), "t0ken") {
+		t.Errorf("secret leaked:\n%s", out.String())
+	}
+
+	if err = ParseFlags(set, []string{"--password", "vault:secret/data/missing#x"}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSecretResolverContext(t *testing.T) {
+	defer func() { secretResolvers = nil }()
+	RegisterSecretResolver("slow", SecretResolverFunc(func(ctx context.Context, ref string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}))
+
+	var c struct{ Password string }
+	set := pflag.NewFlagSet("slow", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := ParseFlagsContext(ctx, set, []string{"--password", "slow:x"}); err == nil {
+		t.Errorf("expected error")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("resolve not cancelled with the parse context: %v", d)
+	}
+
+	Init("vault", map[string]string{"VAULT_ADDR": "http://vault:8200", "VAULT_TOKEN": "tk"})
+	defer Init("", nil)
+	if r := NewVaultResolver(); r.Addr != "http://vault:8200" || r.Token != "tk" {
+		t.Errorf("resolver = %+v", r)
+	}
+}
+
+func TestBindConfigEnv(t *testing.T) {
+	var c struct {
+		Port int
+		Host string
+		DB   struct{ User string }
+	}
+	set := pflag.NewFlagSet("b64", pflag.ContinueOnError)
+	StructBind(&c, set)
+
+	yml := "port: 9090\nhost: db\ndb:\n  user: root\n"
+	t.Setenv("APP_CONFIG_B64", base64.StdEncoding.EncodeToString([]byte(yml)))
+	if err := BindConfigEnv(&c, "APP_CONFIG_B64", set); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 9090 || c.Host != "db" || c.DB.User != "root" {
+		t.Errorf("yaml: %+v", c)
+	}
+	if src, key := FlagSource("db.user", set); src != SourceConfig || key != "APP_CONFIG_B64" {
+		t.Errorf("source = %s %s", src, key)
+	}
+
+	t.Setenv("APP_CONFIG_B64", base64.RawURLEncoding.EncodeToString([]byte(`{"port": 8080}`)))
+	if err := BindConfigEnv(&c, "APP_CONFIG_B64", set); err != nil || c.Port != 8080 {
+		t.Errorf("json: %+v, err = %v", c, err)
+	}
+
+	t.Setenv("APP_CONFIG_B64", "not base64!")
+	if err := BindConfigEnv(&c, "APP_CONFIG_B64", set); err == nil {
+		t.Errorf("want decode error")
+	}
+}
+
+func TestNew(t *testing.T) {
+	old := Version()
+	defer func() { version = old }()
+	Version("9.9.9")
+
+	var a, b struct {
+		Port int
+		Host string
+	}
+	s1, s2 := New("svc1"), New("svc2").SetVersion("1.0.0")
+	defer s1.Release()
+	defer s2.Release()
+
+	if err := s1.Struct(&a); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.Struct(&b); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.ParseArgs([]string{"--port", "1"}); err != nil || a.Port != 1 || b.Port != 0 {
+		t.Errorf("a = %+v, b = %+v, err = %v", a, b, err)
+	}
+	if err := s2.ParseArgs([]string{"--host", "h"}); err != nil || b.Host != "h" || a.Host != "" {
+		t.Errorf("a = %+v, b = %+v, err = %v", a, b, err)
+	}
+	if pflag.CommandLine.Lookup("port") != nil {
+		t.Errorf("global flag set modified")
+	}
+
+	if s1.Lookup("version") != nil || strings.Contains(s1.Help(), "9.9.9") {
+		t.Errorf("s1 uses the global version:\n%s", s1.Help())
+	}
+	if !strings.HasPrefix(s2.Help(), "svc2 -- version 1.0.0") {
+		t.Errorf("s2 help:\n%s", s2.Help())
+	}
+	if err := s2.ParseArgs([]string{"--version"}); err != ErrVersion {
+		t.Errorf("err = %v", err)
+	}
+	if err := s1.ParseArgs([]string{"--unknown"}); err == nil {
+		t.Errorf("want error")
+	}
+}
+
+func TestVersionTemplate(t *testing.T) {
+	defer func(tpl *template.Template, bt string) { versionTemplate, buildTime = tpl, bt }(versionTemplate, buildTime)
+	BuildTime("2026-01-02")
+
+	s := New("app").SetVersion("1.2.3")
+	defer s.Release()
+
+	if err := VersionTemplate("{{.Name}} {{.Version}} built {{.BuildTime}} with {{.GoVersion}}"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := RenderVersion(s.FlagSet), "app 1.2.3 built 2026-01-02 with "+runtime.Version(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// 模板错误时返回错误，保留原来的模板
+	if err := VersionTemplate("{{.Name"); err == nil {
+		t.Errorf("want error for bad template")
+	}
+	if got := RenderVersion(s.FlagSet); !strings.HasPrefix(got, "app 1.2.3 built") {
+		t.Errorf("template replaced: %q", got)
+	}
+}
+
+func TestRenderVersion(t *testing.T) {
+	var c struct{ Port int }
+	s := New("app").SetVersion("1.0.0")
+	defer s.Release()
+	s.Struct(&c)
+
+	// 只返回内容，不输出到标准错误
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = f
+	version, help := RenderVersion(s.FlagSet), s.Help()
+	os.Stderr = stderr
+	f.Close()
+
+	if data, _ := os.ReadFile(f.Name()); len(data) > 0 {
+		t.Errorf("written to stderr: %s", data)
+	}
+	if !strings.HasPrefix(version, "app -- version 1.0.0\n") || !strings.Contains(version, "go version: "+runtime.Version()) {
+		t.Errorf("version:\n%s", version)
+	}
+	if !strings.HasPrefix(help, "app -- version 1.0.0\n") || !strings.Contains(help, "--port") {
+		t.Errorf("help:\n%s", help)
+	}
+}
+
+func TestVersionFromBuildInfo(t *testing.T) {
+	defer func(v, bt string) { version, buildTime, readBuildInfo = v, bt, debug.ReadBuildInfo }(version, buildTime)
+
+	var bi *debug.BuildInfo
+	readBuildInfo = func() (*debug.BuildInfo, bool) { return bi, bi != nil }
+	info := func(mainVersion string, settings ...string) *debug.BuildInfo {
+		b := &debug.BuildInfo{Main: debug.Module{Version: mainVersion}}
+		for i := 0; i+1 < len(settings); i += 2 {
+			b.Settings = append(b.Settings, debug.BuildSetting{Key: settings[i], Value: settings[i+1]})
+		}
+		return b
+	}
+	const rev = "0123456789abcdef0123456789abcdef01234567"
+
+	for _, tc := range []struct {
+		bi        *debug.BuildInfo
+		version   string
+		buildTime string
+	}{
+		// 没有编译信息时不修改
+		{nil, "", ""},
+		{info("v1.2.0", "vcs.revision", rev, "vcs.time", "2026-01-02T03:04:05Z"), "v1.2.0", "2026-01-02T03:04:05Z"},
+		// go run 和本地编译的版本为 (devel)，使用提交的前 12 位
+		{info("(devel)", "vcs.revision", rev), "0123456789ab", ""},
+		{info("(devel)", "vcs.revision", rev, "vcs.modified", "true"), "0123456789ab-dirty", ""},
+		{info("(devel)"), "", ""},
+	} {
+		bi, version, buildTime = tc.bi, "", ""
+		VersionFromBuildInfo()
+		if version != tc.version || buildTime != tc.buildTime {
+			t.Errorf("%+v: version = %q, build time = %q", tc.bi, version, buildTime)
+		}
+	}
+
+	// 已经设置的值不会被覆盖
+	bi, version, buildTime = info("v1.2.0", "vcs.time", "2026-01-02"), "v9", "today"
+	VersionFromBuildInfo()
+	if version != "v9" || buildTime != "today" {
+		t.Errorf("version = %q, build time = %q", version, buildTime)
+	}
+
+	bi = info("v1.2.0", "vcs.revision", rev, "vcs.modified", "true")
+	if got := versionInfo("app", "v1.2.0"); got.Revision != rev || !got.Dirty {
+		t.Errorf("info = %+v", got)
+	}
+	if got := RenderVersion(); !strings.Contains(got, "revision:   "+rev+" (dirty)") {
+		t.Errorf("version:\n%s", got)
+	}
+}
+
+func TestExample(t *testing.T) {
+	var c struct{ Port int }
+	s := New("app")
+	defer s.Release()
+	s.Struct(&c)
+	if strings.Contains(s.Help(), "EXAMPLES") {
+		t.Errorf("empty examples section:\n%s", s.Help())
+	}
+
+	Example("run with custom port", "app --port 9090", s.FlagSet)
+	Example("", "app", s.FlagSet)
+	help := s.Help()
+	want := "EXAMPLES:\n      # run with custom port\n      app --port 9090\n\n      app\n"
+	if !strings.Contains(help, want) || !strings.HasSuffix(strings.TrimSpace(help), "app") {
+		t.Errorf("help:\n%s", help)
+	}
+	if got := strings.Index(help, "EXAMPLES"); got < strings.Index(help, "--port") {
+		t.Errorf("examples before options:\n%s", help)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	defer func() { messages = map[string]string{} }()
+
+	var c struct {
+		Timeout time.Duration
+		Name    string `usage:"name"`
+		Debug   bool
+		Verbose int `count:"true"`
+	}
+	c.Timeout = time.Second
+	set := pflag.NewFlagSet("i18n", pflag.ContinueOnError)
+	StructBind(&c, set)
+	set.StringSlice("tags", nil, "tags")
+	set.Duration("wait", 0, "wait")
+
+	SetMessages(MessagesZH)
+	TypeName("duration", "时长")
+	help := RenderHelp(set)
+
+	for _, s := range []string{"用法:", "参数:", "--timeout 时长", "(default 1s)", "--name 字符串", "--tags 字符串列表", "--debug ", "--verbose count"} {
+		if !strings.Contains(help, s) {
+			t.Errorf("missing %q in:\n%s", s, help)
+		}
+	}
+	if strings.Contains(help, "default 0s") || strings.Contains(help, "default []") {
+		t.Errorf("zero default shown:\n%s", help)
+	}
+	// 按显示宽度对齐
+	cols := map[int]bool{}
+	for _, line := range strings.Split(help, "\n") {
+		for _, usage := range []string{" Debug", " name", " tags", " Timeout", " Verbose", " wait"} {
+			if strings.HasPrefix(line, "      --") && strings.Contains(line, usage) {
+				cols[displayWidth(line[:strings.Index(line, usage)])] = true
+			}
+		}
+	}
+	if len(cols) != 1 {
+		t.Errorf("misaligned:\n%s", help)
+	}
+
+	if f := set.Lookup("timeout"); f.Value.Type() != "duration" {
+		t.Errorf("type not restored: %s", f.Value.Type())
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	set := pflag.NewFlagSet("concurrent", pflag.ContinueOnError)
+
+	var c struct {
+		Port int
+		Tags []string
+	}
+	var a struct{ A int }
+	var b struct{ B string }
+
+	// 同一个参数集合和不同的参数集合上并发绑定
+	var wg sync.WaitGroup
+	for _, ptr := range []any{&c, &a, &b} {
+		wg.Add(2)
+		go func(ptr any) {
+			defer wg.Done()
+			if err := StructBindE(ptr, set); err != nil {
+				t.Error(err)
+			}
+		}(ptr)
+		go func() {
+			defer wg.Done()
+			var c struct{ Port int }
+			s := New("x")
+			defer s.Release()
+			if err := s.Struct(&c); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ParseFlags(set, []string{"--port", "1", "--a", "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 解析之后并发读取和热更新
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for n := 0; ctx.Err() == nil; n++ {
+				SetAll(set, map[string]string{"port": fmt.Sprint(n), "tags": "a,b"}, SourceConfig)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				Values(set)
+				FlagSource("port", set)
+				_ = set.Lookup("tags").Value.String()
+			}
+		}()
+	}
+
+	// 输出和导出时同时标记敏感参数
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		MarkSecret(set, "port", "a")
+	}()
+	go func() {
+		defer wg.Done()
+		for ctx.Err() == nil {
+			ExecArgs(set)
+			InheritEnv(nil, set)
+			SummaryBanner(set)
+			GenMarkdown(io.Discard, set)
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	wg.Wait()
+}
+
+func TestPlainHelp(t *testing.T) {
+	var c struct {
+		Port     int    `flag:"p,port" env:"APP_PORT" usage:"listen port"`
+		Password string `secret:"true"`
+		Debug    bool
+		Old      string `deprecated:"use --port"`
+	}
+	c.Port, c.Password = 80, "p@ss"
+	set := pflag.NewFlagSet("plain", pflag.ContinueOnError)
+	StructBind(&c, set)
+	ErrorHandling(pflag.ContinueOnError, set)
+
+	want := []string{
+		"\nFlag: --port\nShorthand: -p\nType: int\nDescription: listen port\nDefault: 80\nEnvironment: APP_PORT\n\n",
+		"\nFlag: --password\nType: string\nDescription: Password\nDefault: ******\n\n",
+		"\nFlag: --debug\nDescription: Debug\n\n",
+		"\nFlag: --old\nType: string\nDescription: Old\nDeprecated: use --port\n\n",
+	}
+	help := RenderPlainHelp(set)
+	for _, s := range want {
+		if !strings.Contains(help, s) || strings.Contains(help, "  ") {
+			t.Errorf("missing %q in:\n%s", s, help)
+		}
+	}
+
+	stderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+	err := ParseFlags(set, []string{"--help=plain"})
+	w.Close()
+	os.Stderr = stderr
+	out, _ := io.ReadAll(r)
+
+	if err != pflag.ErrHelp || !strings.Contains(string(out), want[0]) {
+		t.Errorf("err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestGet(t *testing.T) {
+	var c struct {
+		Port    int
+		Timeout time.Duration
+		Tags    []string
+		Addr    *netip.Addr
+	}
+	s := New("get")
+	defer s.Release()
+	s.Struct(&c)
+	s.StringSlice("names", nil, "names")
+	s.Int64("max", 0, "max")
+
+	err := s.ParseArgs([]string{"--port", "8080", "--timeout", "1m", "--tags", "a", "--tags", "b", "--addr", "10.0.0.1", "--names", "x,y", "--max", "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := MustGet[int](s, "port"); v != 8080 {
+		t.Errorf("port = %v", v)
+	}
+	if v := MustGet[time.Duration](s, "timeout"); v != time.Minute {
+		t.Errorf("timeout = %v", v)
+	}
+	if v := MustGet[string](s, "timeout"); v != "1m" {
+		t.Errorf("timeout string = %v", v)
+	}
+	if v := MustGet[int64](s, "port"); v != 8080 {
+		t.Errorf("port int64 = %v", v)
+	}
+	if v := MustGet[[]string](s, "tags"); !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Errorf("tags = %v", v)
+	}
+	if v := MustGet[netip.Addr](s.FlagSet, "addr"); v.String() != "10.0.0.1" {
+		t.Errorf("addr = %v", v)
+	}
+	if v := MustGet[[]string](s, "names"); !reflect.DeepEqual(v, []string{"x", "y"}) {
+		t.Errorf("names = %v", v)
+	}
+	if v := MustGet[int](s, "max"); v != 7 {
+		t.Errorf("max = %v", v)
+	}
+
+	// 程序中修改结构体后读取到最新的值
+	c.Port = 9090
+	if v := MustGet[int](s, "port"); v != 9090 {
+		t.Errorf("port = %v", v)
+	}
+
+	if _, err = Get[int](s, "missing"); err == nil {
+		t.Errorf("want error for undefined flag")
+	}
+	if _, err = Get[int](s, "tags"); err == nil {
+		t.Errorf("want error for invalid type")
+	}
+}
+
+func TestSummaryBanner(t *testing.T) {
+	var c struct {
+		Port     int
+		Host     string
+		Password string `secret:"true"`
+		Motd     string
+		Internal string `hidden:"true"`
+	}
+	c.Port, c.Host, c.Password, c.Motd = 8080, "db", "p@ss", "hello world"
+
+	s := New("app").SetVersion("1.0.0")
+	defer s.Release()
+	s.Struct(&c)
+
+	if got, want := s.SummaryBanner(), `app 1.0.0: port=8080 host=db motd="hello world"`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	var m struct {
+		Port int    `summary:"true"`
+		Host string `summary:"true"`
+		Long string
+	}
+	m.Host = strings.Repeat("h", 70)
+	m.Long = "x"
+	s2 := New("app")
+	defer s2.Release()
+	s2.Struct(&m)
+	s2.Set("port", "1")
+
+	if got, want := s2.SummaryBanner(), "app: port=1 host="+m.Host; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	m.Host = strings.Repeat("h", 130)
+	if got := s2.SummaryBanner(); got != "app:\n  port=1\n  host="+m.Host {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestVisitItems(t *testing.T) {
+	var c struct {
+		Port     int    `flag:"p,port" env:"APP_PORT" usage:"listen port"`
+		Password string `secret:"true"`
+		Debug    bool   `hidden:"true"`
+		JSON     bool   `exclusive:"format"`
+		YAML     bool   `exclusive:"format"`
+	}
+	c.Port, c.Password = 80, "p@ss"
+	t.Setenv("APP_PORT", "8080")
+
+	s := New("visit")
+	defer s.Release()
+	s.Struct(&c)
+	s.String("plain", "x", "plain `text` flag")
+
+	items := map[string]*ItemInfo{}
+	var names []string
+	s.VisitAll(func(it *ItemInfo) {
+		items[it.Name] = it
+		names = append(names, it.Name)
+	})
+
+	if !reflect.DeepEqual(names, []string{"port", "password", "debug", "json", "yaml", "plain"}) {
+		t.Errorf("names = %v", names)
+	}
+
+	port := items["port"]
+	if port.Shorthand != "p" || port.Usage != "listen port" || port.Type != "int" || !reflect.DeepEqual(port.Env, []string{"APP_PORT"}) ||
+		port.Source != SourceEnv || port.SourceKey != "APP_PORT" || port.Default != "8080" || port.Current != "8080" || port.Flag == nil ||
+		!strings.HasSuffix(port.Owner, ".Port") {
+		t.Errorf("port = %+v", port)
+	}
+	if p := items["password"]; !p.Secret || p.Default != "******" || p.Current != "******" {
+		t.Errorf("password = %+v", p)
+	}
+	if d := items["debug"]; !d.Hidden || d.Type != "" {
+		t.Errorf("debug = %+v", d)
+	}
+	if j := items["json"]; len(j.Groups) != 1 || !strings.Contains(j.Groups[0], "mutually exclusive") {
+		t.Errorf("json = %+v", j)
+	}
+	if p := items["plain"]; p.Usage != "plain text flag" || p.Type != "text" || p.Current != "x" {
+		t.Errorf("plain = %+v", p)
+	}
+}
+
+func TestAddSet(t *testing.T) {
+	type DB struct {
+		Host string `flag:"db.host" env:"DB_HOST"`
+		Pass string `flag:"db.pass" secret:"true"`
+	}
+	var db DB
+	dbSet := New("db")
+	defer dbSet.Release()
+	dbSet.Struct(&db)
+
+	var app struct {
+		Port int `flag:"p,port"`
+	}
+	s := New("app")
+	defer s.Release()
+	s.Struct(&app)
+
+	if err := s.AddSet(dbSet); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ParseArgs([]string{"-p", "80", "--db.host", "pg", "--db.pass", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if app.Port != 80 || db.Host != "pg" || db.Pass != "x" {
+		t.Errorf("app = %+v, db = %+v", app, db)
+	}
+	if !strings.Contains(RenderPlainHelp(s.FlagSet), "Environment: DB_HOST") || strings.Contains(s.SummaryBanner(), "db.pass") {
+		t.Errorf("metadata not merged")
+	}
+
+	// 冲突时不加入任何参数
+	var other struct {
+		Host string `flag:"db.host"`
+		Pid  int    `flag:"p,pid"`
+		New  string
+	}
+	o := New("other")
+	defer o.Release()
+	o.Struct(&other)
+	err := s.AddSet(o)
+	if err == nil || !strings.Contains(err.Error(), "--db.host (") || !strings.Contains(err.Error(), ".DB.Host") || !strings.Contains(err.Error(), "-p (") {
+		t.Errorf("err = %v", err)
+	}
+	if s.Lookup("new") != nil {
+		t.Errorf("flags added on collision")
+	}
+
+	c := s.Clone()
+	defer c.Release()
+	c.Lookup("db.host").Hidden = true
+	if s.Lookup("db.host").Hidden || c.Lookup("port").Changed {
+		t.Errorf("clone shares flag definitions")
+	}
+	if err := c.ParseArgs([]string{"--port", "90"}); err != nil || app.Port != 90 {
+		t.Errorf("port = %d, err = %v", app.Port, err)
+	}
+}
+
+func TestRefreshDoc(t *testing.T) {
+	var c struct{ Port int }
+	s := New("app").SetVersion("1.0.0")
+	defer s.Release()
+	s.Struct(&c)
+
+	dir := t.TempDir()
+	doc, comp := filepath.Join(dir, "flags.md"), filepath.Join(dir, "app.bash")
+	gen := func(w io.Writer) error { return GenMarkdown(w, s.FlagSet) }
+	if err := WriteDoc(doc, gen, s.FlagSet); err != nil {
+		t.Fatal(err)
+	}
+	WriteDoc(comp, func(w io.Writer) error { _, err := io.WriteString(w, "complete -W '--port' app\n"); return err }, s.FlagSet)
+
+	data, _ := os.ReadFile(doc)
+	if !strings.HasPrefix(string(data), "<!-- generated by app 1.0.0 -->\n| ") {
+		t.Errorf("doc:\n%s", data)
+	}
+
+	var asked []string
+	yes := func(path, installed, current string) bool { asked = append(asked, installed+">"+current); return true }
+	no := func(path, installed, current string) bool { return false }
+
+	// 版本相同时不询问
+	if updated, err := RefreshDoc(doc, gen, yes, s.FlagSet); updated || err != nil || len(asked) > 0 {
+		t.Errorf("updated = %v, err = %v, asked = %v", updated, err, asked)
+	}
+
+	s.SetVersion("1.1.0")
+	if updated, _ := RefreshDoc(comp, gen, no, s.FlagSet); updated {
+		t.Errorf("updated without consent")
+	}
+	if updated, err := RefreshDoc(doc, gen, yes, s.FlagSet); !updated || err != nil || !reflect.DeepEqual(asked, []string{"1.0.0>1.1.0"}) {
+		t.Errorf("updated = %v, err = %v, asked = %v", updated, err, asked)
+	}
+	data, _ = os.ReadFile(doc)
+	if !strings.HasPrefix(string(data), "<!-- generated by app 1.1.0 -->\n") {
+		t.Errorf("doc:\n%s", data)
+	}
+
+	// 没有版本记录或者不存在的文件不处理
+	plain := filepath.Join(dir, "README.md")
+	os.WriteFile(plain, []byte("hand written\n"), 0o644)
+	for _, path := range []string{plain, filepath.Join(dir, "missing.md")} {
+		if updated, err := RefreshDoc(path, gen, yes, s.FlagSet); updated || err != nil {
+			t.Errorf("%s: updated = %v, err = %v", path, updated, err)
+		}
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	type DB struct {
+		Host    string `flag:"h,host" env:"HOST"`
+		Port    int    `env:"PORT,*OLD_PORT"`
+		User    string `requires:"pass"`
+		Pass    string `secret:"true"`
+		Verbose bool   `negatable:"true"`
+	}
+	t.Setenv("PRIMARY_HOST", "pg1")
+	t.Setenv("REPLICA_OLD_PORT", "6543")
+
+	var primary, replica DB
+	s := New("app")
+	defer s.Release()
+	if err := s.Struct(&primary, WithPrefix("primary-")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 通过其他参数集合加入
+	rs := New("replica")
+	defer rs.Release()
+	rs.Struct(&replica)
+	if err := s.AddSet(rs, WithPrefix("replica.")); err != nil {
+		t.Fatal(err)
+	}
+
+	if primary.Host != "pg1" || replica.Port != 6543 {
+		t.Errorf("env: primary = %+v, replica = %+v", primary, replica)
+	}
+	if s.ShorthandLookup("h") != nil {
+		t.Errorf("prefixed flags should not keep shorthand")
+	}
+
+	err := s.ParseArgs([]string{"--primary-port", "5432", "--replica.host", "pg2", "--no-replica.verbose", "--primary-verbose"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary.Port != 5432 || replica.Host != "pg2" || !primary.Verbose || replica.Verbose {
+		t.Errorf("primary = %+v, replica = %+v", primary, replica)
+	}
+
+	// 依赖的参数名也加上前缀
+	if err = s.ParseArgs([]string{"--replica.user", "u"}); err == nil || !strings.Contains(err.Error(), "--replica.user requires --replica.pass") {
+		t.Errorf("err = %v", err)
+	}
+
+	if err = s.AddSet(rs, WithPrefix("replica.")); err == nil || !strings.Contains(err.Error(), "--replica.host") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestWithPrefixTwice(t *testing.T) {
+	var db struct {
+		Host string
+		Port int
+	}
+	rs := New("db")
+	defer rs.Release()
+	rs.Struct(&db)
+	if err := DefinePreset(rs.FlagSet, "local", map[string]string{"host": "127.0.0.1", "port": "5432"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New("app")
+	defer s.Release()
+	if err := s.AddSet(rs, WithPrefix("primary-")); err != nil {
+		t.Fatal(err)
+	}
+	// 两个前缀写入同一个结构体会互相覆盖
+	if err := s.AddSet(rs, WithPrefix("replica-")); err == nil || !strings.Contains(err.Error(), "already added") {
+		t.Errorf("err = %v", err)
+	}
+	if s.Lookup("replica-host") != nil {
+		t.Errorf("rejected set partially added")
+	}
+
+	if err := s.ParseArgs([]string{"--primary-local", "--primary-port", "6432"}); err != nil {
+		t.Fatal(err)
+	}
+	if db.Host != "127.0.0.1" || db.Port != 6432 {
+		t.Errorf("db = %+v", db)
+	}
+	if f := s.Lookup("primary-local"); f == nil || !strings.Contains(f.Usage, "primary-host=127.0.0.1") {
+		t.Errorf("preset = %+v", f)
+	}
+	if rs.Lookup("host").Changed {
+		t.Errorf("preset wrote to the source set")
+	}
+}
+
+func TestComplete(t *testing.T) {
+	var c struct {
+		CIDR  string `flag:"cidr" example:"10.0.0.0/8" usage:"允许的网段"`
+		Mode  string `flag:"mode" validate:"oneof=fast slow" example:"fast"`
+		Debug bool   `flag:"debug"`
+		Level int    `flag:"level,l"`
+	}
+	s := New("test")
+	defer s.Release()
+	s.Struct(&c)
+
+	// 示例只在参数说明中显示一次，不重复出现在 EXAMPLES 中
+	if usage := s.Lookup("cidr").Usage; !strings.Contains(usage, "(example: 10.0.0.0/8)") {
+		t.Errorf("usage = %q", usage)
+	}
+	if help := s.Help(); strings.Count(help, "10.0.0.0/8") != 1 || strings.Contains(help, msg("EXAMPLES")) {
+		t.Errorf("help = %s", help)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--c"}, []string{"--cidr"}},
+		{[]string{"--cidr", ""}, []string{"10.0.0.0/8"}},
+		{[]string{"--cidr="}, []string{"--cidr=10.0.0.0/8"}},
+		{[]string{"--mode", "s"}, []string{"slow"}},
+		{[]string{"--debug", ""}, nil},
+		{[]string{"--debug="}, []string{"--debug=true", "--debug=false"}},
+		{[]string{"-l", ""}, nil},
+		{[]string{"arg"}, nil},
+	} {
+		if got := s.Complete(tc.args); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Complete(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+
+	var info *ItemInfo
+	s.VisitAll(func(it *ItemInfo) {
+		if it.Name == "cidr" {
+			info = it
+		}
+	})
+	if info == nil || info.Example != "10.0.0.0/8" {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestDuplicateFlag(t *testing.T) {
+	type A struct {
+		Port int `flag:"port,p"`
+	}
+	type B struct {
+		Port int `flag:"port"`
+	}
+	type C struct {
+		Peer string `flag:"peer,p"`
+	}
+
+	set := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	if err := StructBindE(&A{}, set); err != nil {
+		t.Fatal(err)
+	}
+
+	err := StructBindE(&B{}, set)
+	if err == nil || !strings.Contains(err.Error(), "flags.A.Port") || !strings.Contains(err.Error(), "flags.B.Port") {
+		t.Errorf("err = %v", err)
+	}
+
+	err = StructBindE(&C{}, set)
+	if err == nil || !strings.Contains(err.Error(), "-p") || !strings.Contains(err.Error(), "flags.C.Peer") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestDropShorthandConflicts(t *testing.T) {
+	type A struct {
+		Port int `flag:"port,p"`
+	}
+	type B struct {
+		Peer string `flag:"peer,p"`
+	}
+	type C struct {
+		Path string `flag:"path,p"`
+	}
+
+	s := New("test")
+	defer s.Release()
+	DropShorthandConflicts(s.FlagSet)
+	s.Struct(&A{})
+	if err := s.Struct(&B{}); err != nil {
+		t.Fatal(err)
+	}
+	if f := s.Lookup("peer"); f == nil || f.Shorthand != "" {
+		t.Errorf("peer = %+v", f)
+	}
+	if f := s.ShorthandLookup("p"); f == nil || f.Name != "port" {
+		t.Errorf("-p = %+v", f)
+	}
+
+	other := New("other")
+	defer other.Release()
+	other.Struct(&C{})
+	if err := s.AddSet(other); err != nil {
+		t.Fatal(err)
+	}
+	if f := s.Lookup("path"); f == nil || f.Shorthand != "" {
+		t.Errorf("path = %+v", f)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	var c struct {
+		Listen string `flag:"listen" alias:"addr,bind"`
+		Debug  bool   `flag:"debug" alias:"verbose"`
+	}
+	s := New("test")
+	defer s.Release()
+	s.Struct(&c)
+
+	if f := s.Lookup("addr"); f == nil || !f.Hidden || !strings.Contains(f.Deprecated, "--listen") {
+		t.Fatalf("addr = %+v", f)
+	}
+
+	if err := s.ParseArgs([]string{"--bind", ":80", "--verbose"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Listen != ":80" || !c.Debug {
+		t.Errorf("c = %+v", c)
+	}
+	if !s.Lookup("listen").Changed {
+		t.Errorf("listen should be changed")
+	}
+
+	type dup struct {
+		Addr string `flag:"addr"`
+	}
+	if err := s.Struct(&dup{}); err == nil {
+		t.Errorf("alias should conflict with --addr")
+	}
+}
+
+func TestStats(t *testing.T) {
+	t.Setenv("STATS_HOST", "db")
+	var c struct {
+		Host string `flag:"host" env:"STATS_HOST"`
+		Port int    `flag:"port"`
+		Name string `flag:"name"`
+	}
+	s := New("test")
+	defer s.Release()
+	s.Struct(&c)
+	BindJSON(&c, "json", "", "", s.FlagSet)
+
+	if err := s.ParseArgs([]string{"--port", "80", "--json", `{"name":"x"}`}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := s.Stats()
+	if stats.Duration <= 0 {
+		t.Errorf("duration = %v", stats.Duration)
+	}
+	if _, ok := stats.Loads[SourceEnv]; !ok {
+		t.Errorf("loads = %v", stats.Loads)
+	}
+	if _, ok := stats.Loads[SourceConfig]; !ok {
+		t.Errorf("loads = %v", stats.Loads)
+	}
+	if stats.Counts[SourceEnv] != 1 || stats.Counts[SourceFlag] != 1 || stats.Counts[SourceConfig] != 1 {
+		t.Errorf("counts = %v", stats.Counts)
+	}
+}
+
+func TestInheritEnv(t *testing.T) {
+	type conf struct {
+		Host string   `flag:"host" env:"INHERIT_HOST"`
+		Port int      `flag:"port" env:"INHERIT_PORT"`
+		Tags []string `flag:"tag" env:"INHERIT_TAGS"`
+		Name string   `flag:"name"`
+	}
+
+	var parent conf
+	s := New("parent")
+	defer s.Release()
+	s.Struct(&parent)
+	if err := s.ParseArgs([]string{"--host", "db", "--tag", "a", "--tag", "b", "--name", "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	env := InheritEnv([]string{"PATH=/bin", "INHERIT_HOST=old"}, s.FlagSet)
+	want := []string{"PATH=/bin", "INHERIT_HOST=db", InheritedEnvKey + "=INHERIT_HOST"}
+	if !reflect.DeepEqual(env, want) {
+		t.Fatalf("env = %q", env)
+	}
+
+	for _, kv := range append(env, "INHERIT_PORT=8080") {
+		k, v, _ := strings.Cut(kv, "=")
+		t.Setenv(k, v)
+	}
+
+	var child conf
+	c := New("child")
+	defer c.Release()
+	c.Struct(&child)
+	if src, key := FlagSource("host", c.FlagSet); child.Host != "db" || src != SourceParent || key != "INHERIT_HOST" {
+		t.Errorf("host = %q, source = %v %s", child.Host, src, key)
+	}
+	if src, _ := FlagSource("port", c.FlagSet); src != SourceEnv {
+		t.Errorf("port source = %v", src)
+	}
+}
+
+func TestNormalizeNames(t *testing.T) {
+	var c struct {
+		MaxConn int  `flag:"max-conn"`
+		DryRun  bool `flag:"dry_run"`
+	}
+	s := New("test")
+	defer s.Release()
+	s.Struct(&c)
+
+	if err := s.ParseArgs([]string{"--max_conn=10", "--dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxConn != 10 || !c.DryRun {
+		t.Errorf("c = %+v", c)
+	}
+
+	NormalizeNames(nil, s.FlagSet)
+	if err := s.ParseArgs([]string{"--max_conn", "20"}); err == nil {
+		t.Errorf("normalization should be disabled")
+	}
+}
+
+func TestIgnoreCase(t *testing.T) {
+	var c struct {
+		Port   int  `flag:"port,p"`
+		DryRun bool `flag:"dry-run"`
+	}
+	s := New("test")
+	defer s.Release()
+	s.Struct(&c)
+
+	if err := s.ParseArgs([]string{"--Port", "80"}); err == nil {
+		t.Errorf("flags should be case sensitive by default")
+	}
+
+	IgnoreCase(s.FlagSet)
+	if err := s.ParseArgs([]string{"--PORT", "8080", "--Dry_Run"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != 8080 || !c.DryRun {
+		t.Errorf("c = %+v", c)
+	}
+	if err := s.ParseArgs([]string{"-P", "1"}); err == nil {
+		t.Errorf("shorthand should stay case sensitive")
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	t.Setenv("EXEC_TOKEN", "s3cret")
+	type conf struct {
+		Host  string   `flag:"host"`
+		Port  int      `flag:"port"`
+		Tags  []string `flag:"tag"`
+		Token string   `flag:"token" env:"EXEC_TOKEN" secret:"true"`
+		Debug bool     `flag:"debug"`
+	}
+
+	parent := conf{Port: 80}
+	s := New("parent")
+	defer s.Release()
+	s.Struct(&parent)
+	if err := s.ParseArgs([]string{"--host", "-db-", "--tag", "a", "--tag", "b", "--debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	args := s.ExecArgs()
+	want := []string{"--host=-db-", "--tag=a", "--tag=b", "--debug=true"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("args = %q", args)
+	}
+
+	env := s.ExecEnv()
+	if !strings.Contains(strings.Join(env, "\n"), "\nEXEC_TOKEN=s3cret\n") {
+		t.Errorf("env should carry the token")
+	}
+
+	child := conf{Port: 80}
+	c := New("child")
+	defer c.Release()
+	c.Struct(&child)
+	if err := c.ParseArgs(args); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(child, parent) {
+		t.Errorf("child = %+v, parent = %+v", child, parent)
+	}
+}
+
+func TestLock(t *testing.T) {
+	dir := t.TempDir()
+	file, lock := dir+"/app.json", dir+"/app.lock"
+	os.WriteFile(file, []byte(`{"host": "db", "token": "s3cret"}`), 0o644)
+
+	type conf struct {
+		Host  string `flag:"host"`
+		Port  int    `flag:"port"`
+		Token string `flag:"token" secret:"true"`
+	}
+	parse := func(args ...string) error {
+		var c conf
+		s := New("test")
+		defer s.Release()
+		s.Struct(&c)
+		BindFile(&c, "config", "", "", "配置文件", s.FlagSet)
+		LockFlags(true, s.FlagSet)
+		return s.ParseArgs(args)
+	}
+
+	if err := parse("--config", file, "--port", "80", "--write-lock", lock); !errors.Is(err, ErrLockWritten) {
+		t.Fatalf("err = %v", err)
+	}
+	data, _ := os.ReadFile(lock)
+	if strings.Contains(string(data), "s3cret") || !strings.Contains(string(data), file) {
+		t.Errorf("lock = %s", data)
+	}
+	var written Lock
+	json.Unmarshal(data, &written)
+	if token := strings.Join(written.Values["token"], ""); written.Salt == "" || !strings.HasPrefix(token, "hmac-sha256:") {
+		t.Errorf("salt = %q, token = %q", written.Salt, token)
+	}
+	if fi, _ := os.Stat(lock); fi.Mode().Perm() != 0o600 {
+		t.Errorf("lock file mode = %v", fi.Mode())
+	}
+
+	if err := parse("--config", file, "--port", "80", "--verify-lock", lock); err != nil {
+		t.Fatal(err)
+	}
+
+	err := parse("--config", file, "--port", "81", "--verify-lock", lock)
+	if err == nil || !strings.Contains(err.Error(), "--port: 80 => 81") {
+		t.Errorf("err = %v", err)
+	}
+
+	os.WriteFile(file, []byte(`{"host": "db", "token": "other"}`), 0o644)
+	err = parse("--config", file, "--port", "80", "--verify-lock", lock)
+	if err == nil || !strings.Contains(err.Error(), "--token: changed") || !strings.Contains(err.Error(), file+": content changed") {
+		t.Errorf("err = %v", err)
+	}
+
+	written.Sources[file] = "unverifiable"
+	data, _ = json.Marshal(written)
+	os.WriteFile(lock, data, 0o600)
+	os.WriteFile(file, []byte(`{"host": "db", "token": "s3cret"}`), 0o644)
+	err = parse("--config", file, "--port", "80", "--verify-lock", lock)
+	if err == nil || !strings.Contains(err.Error(), file+": cannot be verified") || strings.Contains(err.Error(), "--token") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestNormalizeNamesNoInterspersed(t *testing.T) {
+	set := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	set.Int("max-conn", 0, "")
+	Interspersed(false, set)
+
+	if err := ParseFlags(set, []string{"--max_conn", "1", "child", "--max_conn", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := set.Args(); !reflect.DeepEqual(got, []string{"child", "--max_conn", "5"}) {
+		t.Errorf("Args = %q", got)
+	}
+	if got := RawArgs("max-conn", set); !reflect.DeepEqual(got, []string{"--max_conn", "1"}) {
+		t.Errorf("RawArgs = %q", got)
+	}
+}
+
+func TestWatchConfigRemovedKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	os.WriteFile(file, []byte(`{"level": "debug", "port": 2}`), 0o644)
+
+	Init("watch", map[string]string{"WATCH_HOST": "env-host"})
+	defer Init("", nil)
+
+	c := struct {
+		Level string
+		Port  int
+		Host  string `env:"WATCH_HOST"`
+	}{Level: "info"}
+
+	set := pflag.NewFlagSet("watch", pflag.ContinueOnError)
+	StructBind(&c, set)
+	BindFile(&c, "config", "", "", "配置文件", set)
+	if err := ParseFlags(set, []string{"--config", file}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Level != "debug" || c.Port != 2 {
+		t.Fatalf("c = %+v", c)
+	}
+
+	changed := make(chan string, 1)
+	OnSet("level", func(_, new string) { changed <- new }, set)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := WatchConfig(ctx, "config", 10*time.Millisecond, func(err error) { t.Error(err) }, set); err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(file, []byte(`{"port": 3, "host": "config-host"}`), 0o644)
+	select {
+	case level := <-changed:
+		if level != "info" {
+			t.Errorf("level = %s", level)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("removed key not restored")
+	}
+	cancel()
+
+	if c.Port != 3 || c.Host != "env-host" {
+		t.Errorf("c = %+v", c)
+	}
+	if src, _ := FlagSource("level", set); src != SourceDefault {
+		t.Errorf("level source = %v", src)
+	}
+}
+
+func TestNestedValuesPrefixConflict(t *testing.T) {
+	flat := map[string]string{"db": "x", "db.host": "h", "db.port": "1", "cache.size": "2"}
+	want := map[string]any{
+		"db":      "x",
+		"db.host": "h",
+		"db.port": "1",
+		"cache":   map[string]any{"size": "2"},
+	}
+	for i := 0; i < 20; i++ {
+		if got := nest(flat); !reflect.DeepEqual(got, want) {
+			t.Fatalf("nest = %v", got)
+		}
+	}
+}
+
+func TestDuplicateFlagAheadOfTime(t *testing.T) {
+	set := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	set.String("name", "", "")
+
+	var c struct {
+		Host  string `flag:"host,h"`
+		Name  string `flag:"name"`
+		Debug bool   `flag:"debug" alias:"verbose"`
+	}
+	if err := StructBindE(&c, set); err == nil || !strings.Contains(err.Error(), "--name") {
+		t.Fatalf("err = %v", err)
+	}
+	if set.Lookup("host") != nil || set.Lookup("verbose") != nil || len(metaOf(set).structs) != 0 {
+		t.Errorf("struct is half bound")
+	}
+
+	var d struct {
+		Addr string `flag:"addr,a"`
+		Auth string `flag:"auth,a"`
+	}
+	if err := StructBindE(&d, set); err == nil || !strings.Contains(err.Error(), "-a") || set.Lookup("addr") != nil {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestWithPrefixEnvIsolation(t *testing.T) {
+	Init("prefix", map[string]string{"HOST": "plain-host"})
+	defer Init("", nil)
+
+	c := struct {
+		Host string `flag:"host" env:"HOST"`
+	}{Host: "localhost"}
+
+	src := New("db")
+	defer src.Release()
+	src.Struct(&c)
+	if c.Host != "plain-host" {
+		t.Fatalf("host = %q", c.Host)
+	}
+
+	s := New("test")
+	defer s.Release()
+	if err := s.AddSet(src, WithPrefix("replica.")); err != nil {
+		t.Fatal(err)
+	}
+	if c.Host != "localhost" {
+		t.Errorf("unprefixed env leaked: host = %q", c.Host)
+	}
+	if got, _ := FlagSource("replica.host", s.FlagSet); got != SourceDefault {
+		t.Errorf("replica.host source = %v", got)
+	}
+	if got, key := FlagSource("host", src.FlagSet); got != SourceEnv || key != "HOST" {
+		t.Errorf("source set mutated: %v %s", got, key)
+	}
+}
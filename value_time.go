@@ -0,0 +1,76 @@
+package flags
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var typeTime = reflect.TypeOf(time.Time{})
+
+// 定义 time.Time 类型的参数，layouts 为允许的时间格式，第一个用于显示默认值
+//
+// 除 layouts 外还支持 RFC3339、常用的日期格式以及 now, -24h 等相对时间
+func TimeVarP(set *FlagSet, p *time.Time, name, shorthand string, value time.Time, usage string, layouts ...string) *Flag {
+	if err := checkName(set, name); err != nil {
+		panic(err)
+	}
+
+	*p = value
+	v := newValue(reflect.ValueOf(p).Elem(), typeTime)
+	if len(layouts) > 0 {
+		v.setHooks(timeLayouts(layouts))
+	}
+	return set.VarPF(v, name, shorthand, usage)
+}
+
+// 同 TimeVarP，不设置短名称
+func TimeVar(set *FlagSet, p *time.Time, name string, value time.Time, usage string, layouts ...string) *Flag {
+	return TimeVarP(set, p, name, "", value, usage, layouts...)
+}
+
+// 按照 layouts 转换时间的输入输出
+func timeLayouts(layouts []string) (parse func(string) (string, error), format func(string) string) {
+	parse = func(s string) (string, error) {
+		if _, ok, _ := rParseRelativeTime(s); ok {
+			return s, nil
+		}
+		for _, layout := range layouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t.Format(time.RFC3339Nano), nil
+			}
+		}
+		if _, err := rParseTime(s); err != nil {
+			return s, fmt.Errorf("time %q not match layout %s", s, strings.Join(layouts, " | "))
+		}
+		return s, nil
+	}
+
+	format = func(s string) string {
+		if t, err := rParseTime(s); err == nil && !t.IsZero() {
+			return t.Format(layouts[0])
+		}
+		return s
+	}
+	return
+}
+
+// 字段标签 `layout:"2006-01-02|01/02/2006"`，多个格式以 `|` 分隔
+func fieldLayouts(field *FlagField) {
+	tag := getTag(field.Field.Tag, _TAG_LAYOUT)
+	if tag == "" {
+		return
+	}
+
+	var layouts []string
+	for _, layout := range strings.Split(tag, "|") {
+		if layout = strings.TrimSpace(layout); layout != "" {
+			layouts = append(layouts, layout)
+		}
+	}
+
+	if len(layouts) > 0 && baseType(field.Value.typ) == typeTime {
+		field.Value.setHooks(timeLayouts(layouts))
+	}
+}
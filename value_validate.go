@@ -0,0 +1,45 @@
+package flags
+
+import "reflect"
+
+// 绑定的结构体(包括嵌套的结构体)实现此接口时，解析完成后会调用 Validate 进行校验
+type Validator interface {
+	Validate() error
+}
+
+// 先校验嵌套的结构体，再校验自身
+func validateStruct(v reflect.Value) (err error) {
+	if v = reflect.Indirect(v); v.Kind() != reflect.Struct || isKnown(v.Type()) {
+		return
+	}
+
+	for i, t := 0, v.Type(); i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		if fv := v.Field(i); fv.Kind() == reflect.Struct || (fv.Kind() == reflect.Pointer && !fv.IsNil()) {
+			if err = validateStruct(fv); err != nil {
+				return
+			}
+		}
+	}
+
+	if v.CanAddr() {
+		if it, ok := v.Addr().Interface().(Validator); ok {
+			return it.Validate()
+		}
+	}
+	if it, ok := v.Interface().(Validator); ok {
+		return it.Validate()
+	}
+	return
+}
+
+func validateStructs(set *FlagSet) (err error) {
+	for _, v := range metaOf(set).structs {
+		if err = validateStruct(v); err != nil {
+			return
+		}
+	}
+	return
+}